@@ -3,10 +3,12 @@ package systemd
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -147,9 +149,12 @@ type CPUUsage struct {
 }
 
 // CPUAcct stores CPU accounting information (e.g. cpu usage) for a control
-// group (cgroup) of tasks. Equivalent to cpuacct.usage_all
+// group (cgroup) of tasks. Equivalent to cpuacct.usage_all. On the unified
+// hierarchy the kernel only reports usage summed across all cores (in
+// cpu.stat), which is stored as the single entry of CPUs and PerCPU is false
 type CPUAcct struct {
-	CPUs []CPUUsage
+	CPUs   []CPUUsage
+	PerCPU bool
 }
 
 // UsageUserNanosecs returns user (e.g. non-kernel) cpu consumption in nanoseconds, across all available cpu
@@ -201,10 +206,48 @@ func ReadFileNoStat(filename string) ([]byte, error) {
 	return ioutil.ReadAll(reader)
 }
 
+// readFlatKeyed reads a cgroup file in the "flat keyed" format, one
+// "key value" pair per line, such as cpu.stat or memory.stat
+func readFlatKeyed(path string) (map[string]uint64, error) {
+	b, err := ReadFileNoStat(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read file %s", path)
+	}
+	return parseFlatKeyed(b, path)
+}
+
+func parseFlatKeyed(b []byte, path string) (map[string]uint64, error) {
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			return nil, errors.Errorf("unable to parse contents of file %s", path)
+		}
+		val, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse %s as uint64 (from %s)", fields[1], path)
+		}
+		values[fields[0]] = val
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "unable to scan file %s", path)
+	}
+	return values, nil
+}
+
 // NewCPUAcct will locate and read the kernel's cpu accounting info for
 // the provided systemd cgroup subpath.
 func NewCPUAcct(cgSubpath string) (*CPUAcct, error) {
-	var cpuUsage CPUAcct
+	unified, err := cgUnifiedCached()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to determine cgroup mounting hierarchy")
+	}
+	if unified == unifModeAll {
+		return newCPUAcctUnified(cgSubpath)
+	}
+
+	cpuUsage := CPUAcct{PerCPU: true}
 
 	cgPath, err := cgGetPath("cpu", cgSubpath, "cpuacct.usage_all")
 	if err != nil {
@@ -261,3 +304,663 @@ func NewCPUAcct(cgSubpath string) (*CPUAcct, error) {
 
 	return &cpuUsage, nil
 }
+
+// newCPUAcctUnified reads cpu usage from cpu.stat, as the unified hierarchy
+// has no cpuacct controller
+func newCPUAcctUnified(cgSubpath string) (*CPUAcct, error) {
+	cgPath, err := cgGetPath("cpu", cgSubpath, "cpu.stat")
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get cpu controller path")
+	}
+
+	// Example cpu.stat
+	// usage_usec 2354671
+	// user_usec 1563211
+	// system_usec 791460
+	// ...
+	stat, err := readFlatKeyed(cgPath)
+	if err != nil {
+		return nil, err
+	}
+	user, ok := stat["user_usec"]
+	if !ok {
+		return nil, errors.Errorf("no user_usec in %s", cgPath)
+	}
+	sys, ok := stat["system_usec"]
+	if !ok {
+		return nil, errors.Errorf("no system_usec in %s", cgPath)
+	}
+
+	return &CPUAcct{
+		CPUs: []CPUUsage{{
+			UserNanosec:   user * 1000,
+			SystemNanosec: sys * 1000,
+		}},
+		PerCPU: false,
+	}, nil
+}
+
+// CPUThrottleStat stores how often the CPU bandwidth controller throttled a
+// control group for exceeding its quota. Equivalent to the throttling
+// fields of cpu.stat
+type CPUThrottleStat struct {
+	// Periods is the number of enforcement periods that have elapsed
+	Periods uint64
+	// ThrottledPeriods is the number of periods the cgroup was throttled in
+	ThrottledPeriods uint64
+	// ThrottledNanosecs is the total time the cgroup's tasks were throttled
+	ThrottledNanosecs uint64
+}
+
+// NewCPUThrottleStat will locate and read the kernel's CPU bandwidth
+// statistics for the provided systemd cgroup subpath. The statistics only
+// exist if the cpu controller is enabled for the cgroup, nil is returned
+// without an error if it is not.
+func NewCPUThrottleStat(cgSubpath string) (*CPUThrottleStat, error) {
+	unified, err := cgUnifiedCached()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to determine cgroup mounting hierarchy")
+	}
+
+	cgPath, err := cgGetPath("cpu", cgSubpath, "cpu.stat")
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get cpu controller path")
+	}
+
+	// Example cpu.stat on the unified hierarchy
+	// usage_usec 2354671
+	// ...
+	// nr_periods 1207
+	// nr_throttled 32
+	// throttled_usec 1563211
+	//
+	// Example cpu.stat on the legacy hierarchy
+	// nr_periods 1207
+	// nr_throttled 32
+	// throttled_time 1563211742
+	stat, err := readFlatKeyed(cgPath)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	periods, ok := stat["nr_periods"]
+	if !ok {
+		return nil, nil
+	}
+	throttleStat := CPUThrottleStat{
+		Periods:          periods,
+		ThrottledPeriods: stat["nr_throttled"],
+	}
+	if unified == unifModeAll {
+		throttleStat.ThrottledNanosecs = stat["throttled_usec"] * 1000
+	} else {
+		throttleStat.ThrottledNanosecs = stat["throttled_time"]
+	}
+
+	return &throttleStat, nil
+}
+
+// MemStat stores memory usage information for a control group (cgroup) of
+// tasks. Equivalent to memory.current and memory.stat on the unified
+// hierarchy, memory.usage_in_bytes and memory.stat on the legacy one
+type MemStat struct {
+	// UsageBytes is the total memory in use by the cgroup, including page cache
+	UsageBytes uint64
+	// PeakBytes is the highest usage recorded, only set if HasPeak
+	PeakBytes uint64
+	HasPeak   bool
+	// SwapBytes is the amount of swap in use, only set if HasSwap. The
+	// legacy hierarchy does not report swap on its own
+	SwapBytes uint64
+	HasSwap   bool
+	// Breakdown splits usage into "anon", "file", "kernel" and "shmem"
+	// memory, kinds the kernel does not report are missing
+	Breakdown map[string]uint64
+	// Events counts how often the cgroup ran into its limits ("low",
+	// "high", "max") and into the OOM killer ("oom", "oom_kill"). The
+	// legacy hierarchy only reports "max" and, since linux 4.13, "oom_kill"
+	Events map[string]uint64
+}
+
+// NewMemStat will locate and read the kernel's memory accounting info for
+// the provided systemd cgroup subpath.
+func NewMemStat(cgSubpath string) (*MemStat, error) {
+	unified, err := cgUnifiedCached()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to determine cgroup mounting hierarchy")
+	}
+	if unified == unifModeAll {
+		return newMemStatUnified(cgSubpath)
+	}
+	return newMemStatLegacy(cgSubpath)
+}
+
+func newMemStatUnified(cgSubpath string) (*MemStat, error) {
+	memStat := MemStat{
+		Breakdown: make(map[string]uint64),
+		Events:    make(map[string]uint64),
+	}
+
+	usage, err := readCgroupUint64("memory", cgSubpath, "memory.current")
+	if err != nil {
+		return nil, err
+	}
+	memStat.UsageBytes = usage
+
+	// memory.peak was only added in linux 5.19
+	if peak, err := readCgroupUint64("memory", cgSubpath, "memory.peak"); err == nil {
+		memStat.PeakBytes = peak
+		memStat.HasPeak = true
+	}
+	// memory.swap.current is missing without swap accounting
+	if swap, err := readCgroupUint64("memory", cgSubpath, "memory.swap.current"); err == nil {
+		memStat.SwapBytes = swap
+		memStat.HasSwap = true
+	}
+
+	cgPath, err := cgGetPath("memory", cgSubpath, "memory.stat")
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get memory controller path")
+	}
+	stat, err := readFlatKeyed(cgPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, kind := range []string{"anon", "file", "shmem"} {
+		if val, ok := stat[kind]; ok {
+			memStat.Breakdown[kind] = val
+		}
+	}
+	if kernel, ok := stat["kernel"]; ok {
+		memStat.Breakdown["kernel"] = kernel
+	} else {
+		// Before linux 5.18 kernel memory is only reported in parts
+		var kernel uint64
+		var found bool
+		for _, part := range []string{"kernel_stack", "pagetables", "percpu", "slab"} {
+			if val, ok := stat[part]; ok {
+				kernel += val
+				found = true
+			}
+		}
+		if found {
+			memStat.Breakdown["kernel"] = kernel
+		}
+	}
+
+	// Example memory.events
+	// low 0
+	// high 0
+	// max 12
+	// oom 1
+	// oom_kill 1
+	cgPath, err = cgGetPath("memory", cgSubpath, "memory.events")
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get memory controller path")
+	}
+	events, err := readFlatKeyed(cgPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range []string{"low", "high", "max", "oom", "oom_kill"} {
+		if val, ok := events[event]; ok {
+			memStat.Events[event] = val
+		}
+	}
+
+	return &memStat, nil
+}
+
+func newMemStatLegacy(cgSubpath string) (*MemStat, error) {
+	memStat := MemStat{
+		Breakdown: make(map[string]uint64),
+		Events:    make(map[string]uint64),
+	}
+
+	usage, err := readCgroupUint64("memory", cgSubpath, "memory.usage_in_bytes")
+	if err != nil {
+		return nil, err
+	}
+	memStat.UsageBytes = usage
+
+	if peak, err := readCgroupUint64("memory", cgSubpath, "memory.max_usage_in_bytes"); err == nil {
+		memStat.PeakBytes = peak
+		memStat.HasPeak = true
+	}
+
+	cgPath, err := cgGetPath("memory", cgSubpath, "memory.stat")
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get memory controller path")
+	}
+	stat, err := readFlatKeyed(cgPath)
+	if err != nil {
+		return nil, err
+	}
+	// The total_ variants include descendant cgroups, like usage_in_bytes does
+	for kind, key := range map[string]string{"anon": "total_rss", "file": "total_cache", "shmem": "total_shmem"} {
+		if val, ok := stat[key]; ok {
+			memStat.Breakdown[kind] = val
+		}
+	}
+	// Kernel memory is accounted separately, if kmem accounting is enabled at all
+	if kernel, err := readCgroupUint64("memory", cgSubpath, "memory.kmem.usage_in_bytes"); err == nil {
+		memStat.Breakdown["kernel"] = kernel
+	}
+
+	// failcnt counts how often usage hit memory.limit_in_bytes, which is
+	// what memory.events calls max on the unified hierarchy
+	if failcnt, err := readCgroupUint64("memory", cgSubpath, "memory.failcnt"); err == nil {
+		memStat.Events["max"] = failcnt
+	}
+	// Example memory.oom_control
+	// oom_kill_disable 0
+	// under_oom 0
+	// oom_kill 1
+	cgPath, err = cgGetPath("memory", cgSubpath, "memory.oom_control")
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get memory controller path")
+	}
+	if oomControl, err := readFlatKeyed(cgPath); err == nil {
+		if oomKill, ok := oomControl["oom_kill"]; ok {
+			memStat.Events["oom_kill"] = oomKill
+		}
+	}
+
+	return &memStat, nil
+}
+
+// readCgroupUint64 reads a cgroup file holding a single number, such as
+// memory.current
+func readCgroupUint64(controller string, cgSubpath string, file string) (uint64, error) {
+	cgPath, err := cgGetPath(controller, cgSubpath, file)
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to get %s controller path", controller)
+	}
+	b, err := ReadFileNoStat(cgPath)
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to read file %s", cgPath)
+	}
+	val, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to parse contents of file %s", cgPath)
+	}
+	return val, nil
+}
+
+// IODeviceStat stores the block IO a control group (cgroup) of tasks
+// issued to one device
+type IODeviceStat struct {
+	Major      uint32
+	Minor      uint32
+	ReadBytes  uint64
+	WriteBytes uint64
+	ReadOps    uint64
+	WriteOps   uint64
+}
+
+// IOStat stores block IO statistics for a control group (cgroup) of tasks.
+// Equivalent to io.stat on the unified hierarchy, blkio.throttle.io_service_bytes
+// and blkio.throttle.io_serviced on the legacy one
+type IOStat struct {
+	Devices []IODeviceStat
+}
+
+// NewIOStat will locate and read the kernel's block IO accounting info for
+// the provided systemd cgroup subpath.
+func NewIOStat(cgSubpath string) (*IOStat, error) {
+	unified, err := cgUnifiedCached()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to determine cgroup mounting hierarchy")
+	}
+	if unified == unifModeAll {
+		return newIOStatUnified(cgSubpath)
+	}
+	return newIOStatLegacy(cgSubpath)
+}
+
+func newIOStatUnified(cgSubpath string) (*IOStat, error) {
+	cgPath, err := cgGetPath("io", cgSubpath, "io.stat")
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get io controller path")
+	}
+
+	// Example io.stat
+	// 8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0
+	// 253:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0
+	b, err := ReadFileNoStat(cgPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read file %s", cgPath)
+	}
+	return parseIOStatUnified(b, cgPath)
+}
+
+func parseIOStatUnified(b []byte, cgPath string) (*IOStat, error) {
+	var ioStat IOStat
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 1 {
+			continue
+		}
+		major, minor, err := parseDeviceNumber(fields[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse contents of file %s", cgPath)
+		}
+		dev := IODeviceStat{Major: major, Minor: minor}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				return nil, errors.Errorf("unable to parse contents of file %s", cgPath)
+			}
+			// Other controllers add their own keys, some of which are not
+			// numbers, such as io.latency's depth=max
+			var val *uint64
+			switch kv[0] {
+			case "rbytes":
+				val = &dev.ReadBytes
+			case "wbytes":
+				val = &dev.WriteBytes
+			case "rios":
+				val = &dev.ReadOps
+			case "wios":
+				val = &dev.WriteOps
+			default:
+				continue
+			}
+			*val, err = strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to parse %s as uint64 (from %s)", kv[1], cgPath)
+			}
+		}
+		ioStat.Devices = append(ioStat.Devices, dev)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "unable to scan file %s", cgPath)
+	}
+
+	return &ioStat, nil
+}
+
+func newIOStatLegacy(cgSubpath string) (*IOStat, error) {
+	bytesStat, err := readBlkioStat(cgSubpath, "blkio.throttle.io_service_bytes")
+	if err != nil {
+		return nil, err
+	}
+	opsStat, err := readBlkioStat(cgSubpath, "blkio.throttle.io_serviced")
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeBlkioStats(bytesStat, opsStat), nil
+}
+
+// mergeBlkioStats combines the bytes and operations read from the blkio
+// files by device, a device may show up in only one of them
+func mergeBlkioStats(bytesStat, opsStat map[[2]uint32]blkioReadWrite) *IOStat {
+	devices := make([][2]uint32, 0, len(bytesStat))
+	for dev := range bytesStat {
+		devices = append(devices, dev)
+	}
+	for dev := range opsStat {
+		if _, ok := bytesStat[dev]; !ok {
+			devices = append(devices, dev)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i][0] != devices[j][0] {
+			return devices[i][0] < devices[j][0]
+		}
+		return devices[i][1] < devices[j][1]
+	})
+
+	var ioStat IOStat
+	for _, dev := range devices {
+		b, ops := bytesStat[dev], opsStat[dev]
+		ioStat.Devices = append(ioStat.Devices, IODeviceStat{
+			Major:      dev[0],
+			Minor:      dev[1],
+			ReadBytes:  b.read,
+			WriteBytes: b.write,
+			ReadOps:    ops.read,
+			WriteOps:   ops.write,
+		})
+	}
+	return &ioStat
+}
+
+// blkioReadWrite holds the Read and Write rows of one device in a blkio file
+type blkioReadWrite struct {
+	read  uint64
+	write uint64
+}
+
+// readBlkioStat reads one of the blkio.throttle files, keyed by device number
+func readBlkioStat(cgSubpath string, file string) (map[[2]uint32]blkioReadWrite, error) {
+	// The _recursive variants, where available, include descendant cgroups,
+	// which matters for slices
+	cgPath, err := cgGetPath("blkio", cgSubpath, file+"_recursive")
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get blkio controller path")
+	}
+	b, err := ReadFileNoStat(cgPath)
+	if err != nil {
+		cgPath, err = cgGetPath("blkio", cgSubpath, file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to get blkio controller path")
+		}
+		b, err = ReadFileNoStat(cgPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read file %s", cgPath)
+		}
+	}
+
+	return parseBlkioStat(b, cgPath)
+}
+
+func parseBlkioStat(b []byte, cgPath string) (map[[2]uint32]blkioReadWrite, error) {
+	// Example blkio.throttle.io_service_bytes
+	// 8:0 Read 1459200
+	// 8:0 Write 314773504
+	// 8:0 Sync 314773504
+	// 8:0 Async 1459200
+	// 8:0 Total 316232704
+	// Total 316232704
+	stat := make(map[[2]uint32]blkioReadWrite)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || (fields[1] != "Read" && fields[1] != "Write") {
+			continue
+		}
+		major, minor, err := parseDeviceNumber(fields[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse contents of file %s", cgPath)
+		}
+		val, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse %s as uint64 (from %s)", fields[2], cgPath)
+		}
+		dev := [2]uint32{major, minor}
+		rw := stat[dev]
+		if fields[1] == "Read" {
+			rw.read = val
+		} else {
+			rw.write = val
+		}
+		stat[dev] = rw
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "unable to scan file %s", cgPath)
+	}
+	return stat, nil
+}
+
+// parseDeviceNumber parses a "major:minor" block device number
+func parseDeviceNumber(s string) (uint32, uint32, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return 0, 0, errors.Errorf("invalid device number %s", s)
+	}
+	major, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "invalid device number %s", s)
+	}
+	minor, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "invalid device number %s", s)
+	}
+	return uint32(major), uint32(minor), nil
+}
+
+// blockDeviceName resolves a block device number to the device's kernel
+// name (e.g. sda) using /sys/dev/block. It returns "" if that fails
+func blockDeviceName(major uint32, minor uint32) string {
+	b, err := ReadFileNoStat(fmt.Sprintf("/sys/dev/block/%d:%d/uevent", major, minor))
+	if err != nil {
+		return ""
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "DEVNAME=") {
+			return strings.TrimPrefix(line, "DEVNAME=")
+		}
+	}
+	return ""
+}
+
+// PressureStat is the Pressure Stall Information the kernel keeps about a
+// single resource (cpu, memory or io) of a cgroup
+type PressureStat struct {
+	// SomeMicrosecs is the total time at least one task in the cgroup was
+	// stalled waiting on the resource
+	SomeMicrosecs uint64
+	// FullMicrosecs is the total time all non-idle tasks in the cgroup were
+	// stalled at once, only set if HasFull
+	FullMicrosecs uint64
+	HasFull       bool
+}
+
+// NewPressureStat will read the pressure stall information of resource for
+// the provided systemd cgroup subpath. PSI only exists on the unified
+// hierarchy and on kernels built and booted with it, so nil is returned
+// without an error if it is not available.
+func NewPressureStat(cgSubpath string, resource string) (*PressureStat, error) {
+	unified, err := cgUnifiedCached()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to determine cgroup mounting hierarchy")
+	}
+	if unified != unifModeAll {
+		return nil, nil
+	}
+
+	cgPath, err := cgGetPath(resource, cgSubpath, resource+".pressure")
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get %s controller path", resource)
+	}
+
+	// Example memory.pressure
+	// some avg10=0.00 avg60=0.00 avg300=0.00 total=1115672
+	// full avg10=0.00 avg60=0.00 avg300=0.00 total=1065233
+	b, err := ReadFileNoStat(cgPath)
+	if err != nil {
+		// The file is missing on kernels without PSI, and fails to read
+		// with EOPNOTSUPP on kernels booted with psi=0
+		if pathErr, ok := err.(*os.PathError); ok && (os.IsNotExist(err) || pathErr.Err == unix.EOPNOTSUPP) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "unable to read file %s", cgPath)
+	}
+	return parsePressureStat(b, cgPath)
+}
+
+func parsePressureStat(b []byte, cgPath string) (*PressureStat, error) {
+	var stat PressureStat
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 1 {
+			continue
+		}
+		var total uint64
+		var found bool
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "total=") {
+				continue
+			}
+			var err error
+			total, err = strconv.ParseUint(strings.TrimPrefix(field, "total="), 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to parse contents of file %s", cgPath)
+			}
+			found = true
+		}
+		if !found {
+			return nil, errors.Errorf("unable to parse contents of file %s", cgPath)
+		}
+		switch fields[0] {
+		case "some":
+			stat.SomeMicrosecs = total
+		case "full":
+			stat.FullMicrosecs = total
+			stat.HasFull = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "unable to scan file %s", cgPath)
+	}
+
+	return &stat, nil
+}
+
+// cgroupPIDs returns the PIDs of all processes in the provided systemd cgroup
+// subpath and in all cgroups below it. Processes are looked up in the
+// cgroup hierarchy systemd uses to track them, which is the named systemd
+// hierarchy unless everything is on the unified hierarchy.
+func cgroupPIDs(cgSubpath string) ([]int, error) {
+	root, err := cgGetPath("systemd", cgSubpath, "")
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get systemd hierarchy path")
+	}
+
+	var pids []int
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Child cgroups come and go while we walk
+			if os.IsNotExist(err) && path != root {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+
+		// Example cgroup.procs
+		// 1234
+		// 1240
+		b, err := ReadFileNoStat(filepath.Join(path, "cgroup.procs"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return errors.Wrapf(err, "unable to read file %s", filepath.Join(path, "cgroup.procs"))
+		}
+		for _, line := range strings.Fields(string(b)) {
+			pid, err := strconv.Atoi(line)
+			if err != nil {
+				return errors.Wrapf(err, "unable to parse contents of file %s", filepath.Join(path, "cgroup.procs"))
+			}
+			pids = append(pids, pid)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pids, nil
+}
@@ -0,0 +1,223 @@
+package systemd
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestReadFlatKeyed(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]uint64
+		wantErr bool
+	}{
+		{
+			name:    "cpu.stat",
+			content: "usage_usec 2000000\nuser_usec 1500000\nsystem_usec 500000\n",
+			want: map[string]uint64{
+				"usage_usec":  2000000,
+				"user_usec":   1500000,
+				"system_usec": 500000,
+			},
+		},
+		{
+			name:    "empty",
+			content: "",
+			want:    map[string]uint64{},
+		},
+		{
+			name:    "missing value",
+			content: "usage_usec\n",
+			wantErr: true,
+		},
+		{
+			name:    "not a number",
+			content: "memory.max max\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "flat-keyed")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			if _, err := f.WriteString(tt.content); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			got, err := readFlatKeyed(f.Name())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readFlatKeyed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readFlatKeyed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := readFlatKeyed("/nonexistent/cpu.stat"); err == nil {
+		t.Error("readFlatKeyed() of a missing file succeeded")
+	}
+}
+
+func TestParseIOStatUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *IOStat
+		wantErr bool
+	}{
+		{
+			name: "two devices",
+			content: "8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0\n" +
+				"253:0 rbytes=1024 wbytes=2048 rios=1 wios=2 dbytes=0 dios=0\n",
+			want: &IOStat{Devices: []IODeviceStat{
+				{Major: 8, Minor: 0, ReadBytes: 1459200, WriteBytes: 314773504, ReadOps: 192, WriteOps: 353},
+				{Major: 253, Minor: 0, ReadBytes: 1024, WriteBytes: 2048, ReadOps: 1, WriteOps: 2},
+			}},
+		},
+		{
+			name:    "io.latency and io.cost keys",
+			content: "8:16 rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0 use_delay=0 delay_nsec=0 depth=max avg_lat=0 win=0 cost.vrate=100.00\n",
+			want: &IOStat{Devices: []IODeviceStat{
+				{Major: 8, Minor: 16, ReadBytes: 4096, WriteBytes: 8192, ReadOps: 1, WriteOps: 2},
+			}},
+		},
+		{
+			name:    "no IO yet",
+			content: "",
+			want:    &IOStat{},
+		},
+		{
+			name:    "bad device number",
+			content: "sda rbytes=1\n",
+			wantErr: true,
+		},
+		{
+			name:    "bad pair",
+			content: "8:0 rbytes\n",
+			wantErr: true,
+		},
+		{
+			name:    "bad counter",
+			content: "8:0 rbytes=max\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseIOStatUnified([]byte(tt.content), "io.stat")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseIOStatUnified() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIOStatUnified() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewIOStatLegacy(t *testing.T) {
+	serviceBytes := `8:0 Read 1459200
+8:0 Write 314773504
+8:0 Sync 314773504
+8:0 Async 1459200
+8:0 Discard 0
+8:0 Total 316232704
+Total 316232704
+`
+	serviced := `8:0 Read 192
+8:0 Write 353
+8:0 Sync 353
+8:0 Async 192
+8:0 Total 545
+8:16 Read 3
+8:16 Write 0
+8:16 Sync 0
+8:16 Async 3
+8:16 Total 3
+Total 548
+`
+
+	bytesStat, err := parseBlkioStat([]byte(serviceBytes), "blkio.throttle.io_service_bytes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBytes := map[[2]uint32]blkioReadWrite{
+		{8, 0}: {read: 1459200, write: 314773504},
+	}
+	if !reflect.DeepEqual(bytesStat, wantBytes) {
+		t.Errorf("parseBlkioStat() = %v, want %v", bytesStat, wantBytes)
+	}
+
+	opsStat, err := parseBlkioStat([]byte(serviced), "blkio.throttle.io_serviced")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := mergeBlkioStats(bytesStat, opsStat)
+	want := &IOStat{Devices: []IODeviceStat{
+		{Major: 8, Minor: 0, ReadBytes: 1459200, WriteBytes: 314773504, ReadOps: 192, WriteOps: 353},
+		{Major: 8, Minor: 16, ReadOps: 3},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeBlkioStats() = %+v, want %+v", got, want)
+	}
+
+	for _, content := range []string{"sda Read 1\n", "8:0 Read lots\n"} {
+		if _, err := parseBlkioStat([]byte(content), "blkio.throttle.io_serviced"); err == nil {
+			t.Errorf("parseBlkioStat(%q) succeeded", content)
+		}
+	}
+}
+
+func TestParsePressureStat(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *PressureStat
+		wantErr bool
+	}{
+		{
+			name: "some and full",
+			content: "some avg10=0.00 avg60=0.00 avg300=0.00 total=1115672\n" +
+				"full avg10=0.00 avg60=0.00 avg300=0.00 total=1065233\n",
+			want: &PressureStat{SomeMicrosecs: 1115672, FullMicrosecs: 1065233, HasFull: true},
+		},
+		{
+			name:    "cpu before 5.13 has no full line",
+			content: "some avg10=1.25 avg60=0.50 avg300=0.10 total=42\n",
+			want:    &PressureStat{SomeMicrosecs: 42},
+		},
+		{
+			name:    "missing total",
+			content: "some avg10=0.00 avg60=0.00 avg300=0.00\n",
+			wantErr: true,
+		},
+		{
+			name:    "bad total",
+			content: "some avg10=0.00 avg60=0.00 avg300=0.00 total=-1\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePressureStat([]byte(tt.content), "memory.pressure")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePressureStat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePressureStat() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
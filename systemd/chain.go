@@ -0,0 +1,208 @@
+package systemd
+
+import (
+	"sync"
+
+	"github.com/coreos/go-systemd/dbus"
+	"github.com/pkg/errors"
+)
+
+// criticalChain holds the units on the critical chain of the current boot,
+// the chain of units each of which had to wait for the next one to become
+// active before it could start, from the default target down. It is
+// computed the same way systemd-analyze critical-chain does, without any
+// fuzz. As the chain cannot change until the next boot it is only computed
+// once per boot, identified by the time booting finished.
+type criticalChain struct {
+	mu     sync.Mutex
+	finish uint64
+	units  map[string]float64
+}
+
+// chainUnitTimes are the timestamps of a unit relevant to the critical chain
+type chainUnitTimes struct {
+	activating uint64
+	activated  uint64
+	after      []string
+}
+
+func newCriticalChain() *criticalChain {
+	return &criticalChain{}
+}
+
+// update recomputes the critical chain if the system booted again since it
+// was last computed. props are the properties of the manager, listed the units
+// listed from systemd, dependencies on units which are not loaded are ignored.
+func (cc *criticalChain) update(sc *systemdConn, conn *dbus.Conn, props map[string]interface{}, listed []dbus.UnitStatus) error {
+	finish, _ := props["FinishTimestampMonotonic"].(uint64)
+	userspace, _ := props["UserspaceTimestampMonotonic"].(uint64)
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if finish == cc.finish {
+		return nil
+	}
+	if finish == 0 {
+		// Still booting
+		cc.finish = 0
+		cc.units = nil
+		return nil
+	}
+
+	obj, err := sc.manager(conn)
+	if err != nil {
+		return err
+	}
+	var target string
+	err = obj.Call("org.freedesktop.systemd1.Manager.GetDefaultTarget", 0).Store(&target)
+	if err != nil {
+		return errors.Wrap(err, "couldn't get default target")
+	}
+
+	isLoaded := make(map[string]bool, len(listed))
+	for _, unit := range listed {
+		if unit.LoadState == "loaded" {
+			isLoaded[unit.Name] = true
+		}
+	}
+
+	w := chainWalker{
+		conn:    conn,
+		loaded:  isLoaded,
+		finish:  finish,
+		times:   make(map[string]*chainUnitTimes),
+		onChain: make(map[string]bool),
+	}
+	if err := w.walk(target); err != nil {
+		return err
+	}
+
+	units := make(map[string]float64, len(w.onChain))
+	for name := range w.onChain {
+		t, err := w.get(name)
+		if err != nil {
+			return err
+		}
+		// Like systemd-analyze, units which took time to start are placed
+		// at the time they started activating, others at the time they
+		// became active. Times are relative to userspace starting.
+		offset := t.activated
+		if t.activated > t.activating && t.activating > 0 {
+			offset = t.activating
+		}
+		if offset > userspace {
+			offset -= userspace
+		} else {
+			offset = 0
+		}
+		units[name] = float64(offset) / 1e6
+	}
+
+	cc.finish = finish
+	cc.units = units
+	return nil
+}
+
+// lookup returns the time the unit started on the critical chain, relative
+// to userspace starting, and whether it is on the chain at all. known is
+// false as long as the chain has not been computed.
+func (cc *criticalChain) lookup(name string) (offset float64, ok bool, known bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.units == nil {
+		return 0, false, false
+	}
+	offset, ok = cc.units[name]
+	return offset, ok, true
+}
+
+// chainWalker follows the After= dependencies which delayed each unit the
+// most, starting from the default target
+type chainWalker struct {
+	conn    *dbus.Conn
+	loaded  map[string]bool
+	finish  uint64
+	times   map[string]*chainUnitTimes
+	onChain map[string]bool
+}
+
+func (w *chainWalker) get(name string) (*chainUnitTimes, error) {
+	if t, ok := w.times[name]; ok {
+		return t, nil
+	}
+
+	props := newUnitProperties(w.conn, name)
+	activating, err := props.getUint64("Unit", "InactiveExitTimestampMonotonic")
+	if err != nil {
+		return nil, err
+	}
+	activated, err := props.getUint64("Unit", "ActiveEnterTimestampMonotonic")
+	if err != nil {
+		return nil, err
+	}
+	after, err := props.get("Unit", "After")
+	if err != nil {
+		return nil, err
+	}
+	afterNames, ok := after.([]string)
+	if !ok {
+		return nil, errors.Errorf("couldn't convert unit's After property %v to []string", after)
+	}
+
+	t := &chainUnitTimes{activating: activating, activated: activated, after: afterNames}
+	w.times[name] = t
+	return t, nil
+}
+
+// inRange tells whether the unit became active during the boot, like
+// systemd-analyze's times_in_range
+func (w *chainWalker) inRange(t *chainUnitTimes) bool {
+	return t.activated > 0 && t.activated <= w.finish
+}
+
+// walk puts name on the chain, followed by the dependencies that became
+// active last before it. Like systemd-analyze without fuzz, all of them are
+// followed if several became active at the very same time.
+func (w *chainWalker) walk(name string) error {
+	if w.onChain[name] {
+		// Ordering cycle
+		return nil
+	}
+	w.onChain[name] = true
+
+	t, err := w.get(name)
+	if err != nil {
+		return err
+	}
+
+	// Units systemd does not have loaded have no timestamps, and asking
+	// for them would load them
+	var latest uint64
+	var next []string
+	for _, dep := range t.after {
+		if !w.loaded[dep] {
+			continue
+		}
+		depTimes, err := w.get(dep)
+		if err != nil {
+			return err
+		}
+		if !w.inRange(depTimes) || depTimes.activated < latest {
+			continue
+		}
+		if depTimes.activated > latest {
+			latest = depTimes.activated
+			next = next[:0]
+		}
+		next = append(next, dep)
+	}
+
+	for _, dep := range next {
+		if err := w.walk(dep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,355 @@
+package systemd
+
+import (
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-systemd/dbus"
+	godbus "github.com/godbus/dbus"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/log"
+)
+
+const (
+	// systemdPrivateAddress is the socket systemd listens on for direct,
+	// dbus-daemon-less connections
+	systemdPrivateAddress = "unix:path=/run/systemd/private"
+
+	// signalBuffer is the number of signals that may be queued up before
+	// godbus starts delivering them out of order
+	signalBuffer = 1024
+
+	// Bounds of the exponential backoff between two attempts to establish
+	// a connection to systemd
+	minReconnectBackoff = 1 * time.Second
+	maxReconnectBackoff = 1 * time.Minute
+)
+
+// systemdConn holds one long-lived connection to systemd which is shared by
+// all scrapes. It watches the underlying bus connections and, once they break
+// (e.g. because dbus-daemon restarted or systemd re-executed itself), throws the
+// connection away and dials a new one on the next call to get. Failed dials
+// are retried with exponential backoff so an unreachable systemd is not
+// hammered by every scrape.
+type systemdConn struct {
+	logger log.Logger
+
+	mu          sync.Mutex
+	conn        *dbus.Conn
+	callBus     *godbus.Conn
+	signalBus   *godbus.Conn
+	broken      <-chan struct{}
+	subscribers []signalSubscriber
+	backoff     time.Duration
+	nextAttempt time.Time
+	established bool
+
+	reconnects    uint64
+	connectErrors uint64
+}
+
+// signalSubscriber is notified about the signals systemd emits. Signals sent
+// while there was no connection are lost, so subscribers are told about every
+// newly established connection and have to treat their state as stale.
+type signalSubscriber interface {
+	// matchRules returns the dbus match rules for the signals of interest
+	matchRules() []string
+	// connected is called whenever a new connection was established
+	connected(conn *dbus.Conn) error
+	// signal is called for every signal received, including ones the
+	// subscriber did not ask for
+	signal(signal *godbus.Signal)
+}
+
+func newSystemdConn(logger log.Logger) *systemdConn {
+	return &systemdConn{logger: logger}
+}
+
+// subscribe registers sub to receive signals on the current connection and on
+// all connections established afterwards.
+func (s *systemdConn) subscribe(sub signalSubscriber) {
+	s.mu.Lock()
+	s.subscribers = append(s.subscribers, sub)
+	conn, signalBus := s.conn, s.signalBus
+	s.mu.Unlock()
+
+	if conn != nil {
+		s.startSignals(conn, signalBus, []signalSubscriber{sub})
+	}
+}
+
+// startSignals sets up the match rules of subs on conn and dispatches
+// incoming signals to them until the connection goes away. It talks to the
+// bus, so it must not be called with s.mu held, lest a slow bus holds up
+// every scrape.
+func (s *systemdConn) startSignals(conn *dbus.Conn, signalBus *godbus.Conn, subs []signalSubscriber) {
+	if len(subs) == 0 {
+		return
+	}
+
+	// Subscribers without match rules only want to hear about the connection
+	var listeners []signalSubscriber
+	for _, sub := range subs {
+		if len(sub.matchRules()) > 0 {
+			listeners = append(listeners, sub)
+		}
+	}
+	if len(listeners) > 0 {
+		signals := make(chan *godbus.Signal, signalBuffer)
+		signalBus.Signal(signals)
+		go func() {
+			for signal := range signals {
+				for _, sub := range listeners {
+					sub.signal(signal)
+				}
+			}
+		}()
+	}
+
+	for _, sub := range subs {
+		for _, rule := range sub.matchRules() {
+			call := signalBus.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, rule)
+			if call.Err != nil {
+				s.logger.Warnf("couldn't add dbus match rule %q: %s", rule, call.Err)
+			}
+		}
+		if err := sub.connected(conn); err != nil {
+			s.logger.Warnf("couldn't set up %T on the new connection: %s", sub, err)
+		}
+	}
+}
+
+// get returns the current connection to systemd, dialing a new one if there
+// is none yet or the previous one broke.
+func (s *systemdConn) get() (*dbus.Conn, error) {
+	s.mu.Lock()
+	conn, dialed, err := s.getLocked()
+	signalBus := s.signalBus
+	subs := append([]signalSubscriber(nil), s.subscribers...)
+	s.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	if dialed {
+		s.startSignals(conn, signalBus, subs)
+	}
+	return conn, nil
+}
+
+// getLocked is get with s.mu held, dialed tells whether conn is new
+func (s *systemdConn) getLocked() (conn *dbus.Conn, dialed bool, err error) {
+	if s.conn != nil {
+		select {
+		case <-s.broken:
+			s.logger.Warn("lost connection to systemd, reconnecting")
+			s.conn.Close()
+			s.conn = nil
+			s.callBus = nil
+			s.signalBus = nil
+		default:
+			return s.conn, false, nil
+		}
+	}
+
+	if wait := time.Until(s.nextAttempt); wait > 0 {
+		return nil, false, errors.Errorf("not connected to systemd, next attempt in %s", wait.Round(time.Millisecond))
+	}
+
+	conn, callBus, signalBus, broken, err := dialSystemd()
+	if err != nil {
+		s.connectErrors++
+		s.backoff *= 2
+		if s.backoff < minReconnectBackoff {
+			s.backoff = minReconnectBackoff
+		}
+		if s.backoff > maxReconnectBackoff {
+			s.backoff = maxReconnectBackoff
+		}
+		s.nextAttempt = time.Now().Add(s.backoff)
+		return nil, false, err
+	}
+
+	if s.established {
+		s.reconnects++
+		s.logger.Info("reconnected to systemd")
+	}
+	s.established = true
+	s.conn = conn
+	s.callBus = callBus
+	s.signalBus = signalBus
+	s.broken = broken
+	s.backoff = 0
+	s.nextAttempt = time.Time{}
+	return conn, true, nil
+}
+
+// invalidate drops conn if it is still the current connection, so that the
+// next call to get dials a new one. It is used when a call failed in a way
+// that suggests the connection is unusable but the bus has not told us so.
+func (s *systemdConn) invalidate(conn *dbus.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != conn || s.conn == nil {
+		return
+	}
+	s.conn.Close()
+	s.conn = nil
+	s.callBus = nil
+	s.signalBus = nil
+}
+
+// manager returns systemd's manager object on the bus underneath conn.
+// go-systemd has no GetDefaultTarget, and its GetManagerProperty returns
+// every value formatted as a string, quoted in the case of strings, and takes
+// a call per property.
+func (s *systemdConn) manager(conn *dbus.Conn) (godbus.BusObject, error) {
+	s.mu.Lock()
+	bus := s.callBus
+	current := s.conn == conn
+	s.mu.Unlock()
+
+	if !current || bus == nil {
+		return nil, errors.New("connection to systemd was closed")
+	}
+	return bus.Object("org.freedesktop.systemd1", "/org/freedesktop/systemd1"), nil
+}
+
+// managerProperties returns all properties of systemd's manager object
+func (s *systemdConn) managerProperties(conn *dbus.Conn) (map[string]interface{}, error) {
+	obj, err := s.manager(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var props map[string]godbus.Variant
+	err = obj.Call("org.freedesktop.DBus.Properties.GetAll", 0, "org.freedesktop.systemd1.Manager").Store(&props)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't get systemd manager properties")
+	}
+
+	values := make(map[string]interface{}, len(props))
+	for name, variant := range props {
+		values[name] = variant.Value()
+	}
+	return values, nil
+}
+
+// stats reports whether we currently hold a connection, how often it had to be
+// re-established and how many attempts to connect failed.
+func (s *systemdConn) stats() (connected bool, reconnects, connectErrors uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	connected = s.conn != nil
+	if connected {
+		select {
+		case <-s.broken:
+			connected = false
+		default:
+		}
+	}
+	return connected, s.reconnects, s.connectErrors
+}
+
+// isConnectionError tells errors of the transport apart from all others,
+// such as errors returned by systemd itself or replies we failed to decode,
+// which leave the connection intact.
+func isConnectionError(err error) bool {
+	cause := errors.Cause(err)
+	switch cause {
+	case godbus.ErrClosed, io.EOF, io.ErrUnexpectedEOF:
+		return true
+	}
+	_, ok := cause.(*net.OpError)
+	return ok
+}
+
+// dialSystemd mirrors dbus.New and dbus.NewSystemdConnection, but keeps hold
+// of the bus connections underneath so we can tell when they go away and
+// receive signals and make calls go-systemd does not support ourselves. The
+// returned channel is closed as soon as any of them is closed.
+func dialSystemd() (*dbus.Conn, *godbus.Conn, *godbus.Conn, <-chan struct{}, error) {
+	if *systemdPrivate {
+		return dialSystemdWith(dialSystemdPrivate)
+	}
+	conn, callBus, signalBus, broken, err := dialSystemdWith(dialSystemBus)
+	if err != nil && os.Geteuid() == 0 {
+		return dialSystemdWith(dialSystemdPrivate)
+	}
+	return conn, callBus, signalBus, broken, err
+}
+
+func dialSystemdWith(dialBus func() (*godbus.Conn, error)) (*dbus.Conn, *godbus.Conn, *godbus.Conn, <-chan struct{}, error) {
+	broken := make(chan struct{})
+	var once sync.Once
+	var buses []*godbus.Conn
+
+	conn, err := dbus.NewConnection(func() (*godbus.Conn, error) {
+		bus, err := dialBus()
+		if err != nil {
+			return nil, err
+		}
+		// godbus closes all signal channels once the connection is closed,
+		// which is the only notification we get about it breaking
+		signals := make(chan *godbus.Signal, 16)
+		bus.Signal(signals)
+		go func() {
+			for range signals {
+			}
+			once.Do(func() { close(broken) })
+		}()
+		buses = append(buses, bus)
+		return bus, nil
+	})
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	// dbus.NewConnection dials the bus used for method calls first and the
+	// one it listens for signals on second
+	return conn, buses[0], buses[len(buses)-1], broken, nil
+}
+
+func dialSystemBus() (*godbus.Conn, error) {
+	bus, err := godbus.SystemBusPrivate()
+	if err != nil {
+		return nil, err
+	}
+	if err := authBus(bus); err != nil {
+		return nil, err
+	}
+	if err := bus.Hello(); err != nil {
+		bus.Close()
+		return nil, err
+	}
+	return bus, nil
+}
+
+// dialSystemdPrivate talks to systemd directly, we skip Hello as there is
+// no dbus-daemon on the other end
+func dialSystemdPrivate() (*godbus.Conn, error) {
+	bus, err := godbus.Dial(systemdPrivateAddress)
+	if err != nil {
+		return nil, err
+	}
+	if err := authBus(bus); err != nil {
+		return nil, err
+	}
+	return bus, nil
+}
+
+// authBus only uses the EXTERNAL method with a hardcoded uid, to avoid a
+// username lookup which requires a dynamically linked libc
+func authBus(bus *godbus.Conn) error {
+	methods := []godbus.Auth{godbus.AuthExternal(strconv.Itoa(os.Getuid()))}
+	if err := bus.Auth(methods); err != nil {
+		bus.Close()
+		return err
+	}
+	return nil
+}
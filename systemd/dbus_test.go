@@ -0,0 +1,34 @@
+package systemd
+
+import (
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	godbus "github.com/godbus/dbus"
+	"github.com/pkg/errors"
+)
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "closed", err: godbus.ErrClosed, want: true},
+		{name: "EOF", err: io.EOF, want: true},
+		{name: "wrapped EOF", err: errors.Wrap(io.EOF, "couldn't list units"), want: true},
+		{name: "broken pipe", err: &net.OpError{Op: "write", Net: "unix", Err: syscall.EPIPE}, want: true},
+		{name: "systemd error", err: godbus.Error{Name: "org.freedesktop.systemd1.NoSuchUnit"}, want: false},
+		{name: "decode error", err: errors.New("dbus.Store: type mismatch"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isConnectionError(tt.err); got != tt.want {
+				t.Errorf("isConnectionError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
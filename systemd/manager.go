@@ -0,0 +1,67 @@
+package systemd
+
+import (
+	"sync"
+
+	"github.com/coreos/go-systemd/dbus"
+	godbus "github.com/godbus/dbus"
+)
+
+// systemStates are the states SystemState can be in, see
+// systemctl is-system-running
+var systemStates = []string{"initializing", "starting", "running", "degraded", "maintenance", "stopping", "offline", "unknown"}
+
+// defaultAccountingProperties maps the manager properties holding the
+// default accounting settings to the resource label they are exported with
+var defaultAccountingProperties = map[string]string{
+	"DefaultCPUAccounting":     "cpu",
+	"DefaultMemoryAccounting":  "memory",
+	"DefaultIOAccounting":      "io",
+	"DefaultBlockIOAccounting": "blockio",
+	"DefaultTasksAccounting":   "tasks",
+	"DefaultIPAccounting":      "ip",
+}
+
+// reloadCounter counts the daemon-reloads systemd announces with the
+// Reloading signal. Reloads which happen while we are not connected are
+// not counted.
+// On the system bus systemd only emits its signals while at least one client
+// subscribed to them, which systemd-logind always does. We only listen and
+// do not subscribe ourselves, as that would make systemd broadcast every
+// change of every unit for our sake.
+type reloadCounter struct {
+	mu      sync.Mutex
+	reloads uint64
+}
+
+func (r *reloadCounter) matchRules() []string {
+	return []string{
+		"type='signal',interface='org.freedesktop.systemd1.Manager',member='Reloading'",
+	}
+}
+
+func (r *reloadCounter) connected(conn *dbus.Conn) error {
+	return nil
+}
+
+func (r *reloadCounter) signal(signal *godbus.Signal) {
+	if signal.Name != signalReloading || len(signal.Body) < 1 {
+		return
+	}
+	// Reloading is sent with true when a reload starts and with false
+	// once it is done
+	if active, ok := signal.Body[0].(bool); !ok || !active {
+		return
+	}
+
+	r.mu.Lock()
+	r.reloads++
+	r.mu.Unlock()
+}
+
+func (r *reloadCounter) count() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.reloads
+}
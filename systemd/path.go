@@ -0,0 +1,86 @@
+package systemd
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-systemd/dbus"
+)
+
+// pathResults are the values the Result property of path units can take
+var pathResults = []string{"success", "resources", "start-limit-hit", "unit-start-limit-hit", "trigger-limit-hit"}
+
+// pathTriggers counts how often path units triggered their unit. systemd
+// does not keep count itself, so a trigger is inferred from the triggered
+// unit having been started again, as told by its InactiveExitTimestamp.
+// Units started by other means than the path unit are counted as well.
+type pathTriggers struct {
+	mu      sync.Mutex
+	entries map[string]pathTrigger
+}
+
+type pathTrigger struct {
+	lastTrigger uint64
+	count       uint64
+}
+
+func newPathTriggers() *pathTriggers {
+	return &pathTriggers{entries: make(map[string]pathTrigger)}
+}
+
+// observe records the time the unit triggered by a path unit was last
+// started and returns the number of triggers seen since the exporter
+// started. The start seen first is not counted, as it may well have happened
+// long before.
+func (p *pathTriggers) observe(name string, lastTrigger uint64) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, known := p.entries[name]
+	if known && lastTrigger != entry.lastTrigger && lastTrigger > 0 {
+		entry.count++
+	}
+	entry.lastTrigger = lastTrigger
+	p.entries[name] = entry
+
+	return entry.count
+}
+
+// retain forgets about all path units but the given units
+func (p *pathTriggers) retain(units []dbus.UnitStatus) {
+	names := make(map[string]bool, len(units))
+	for _, unit := range units {
+		names[unit.Name] = true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for name := range p.entries {
+		if !names[name] {
+			delete(p.entries, name)
+		}
+	}
+}
+
+// formatPaths turns the Paths property, a list of (condition, path) tuples,
+// into a list like "PathExists=/var/spool/foo"
+func formatPaths(val interface{}) string {
+	entries, _ := val.([][]interface{})
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if len(entry) < 2 {
+			continue
+		}
+		condition, ok := entry[0].(string)
+		if !ok {
+			continue
+		}
+		path, ok := entry[1].(string)
+		if !ok {
+			continue
+		}
+		paths = append(paths, condition+"="+path)
+	}
+	return strings.Join(paths, ";")
+}
@@ -0,0 +1,119 @@
+package systemd
+
+import (
+	"github.com/coreos/go-systemd/dbus"
+	"github.com/pkg/errors"
+)
+
+// unitProperties is a snapshot of a unit's dbus properties. Rather than
+// asking systemd for every property separately, all properties of an
+// interface are fetched with a single GetAll call the first time one of
+// them is needed, and served from memory afterwards.
+// A unitProperties is not safe for concurrent use, it is meant to be
+// created and consumed by the goroutine collecting a single unit.
+type unitProperties struct {
+	conn  *dbus.Conn
+	name  string
+	props map[string]map[string]interface{}
+}
+
+func newUnitProperties(conn *dbus.Conn, name string) *unitProperties {
+	return &unitProperties{
+		conn:  conn,
+		name:  name,
+		props: make(map[string]map[string]interface{}, 2),
+	}
+}
+
+func (p *unitProperties) load(iface string) (map[string]interface{}, error) {
+	if props, ok := p.props[iface]; ok {
+		return props, nil
+	}
+
+	var props map[string]interface{}
+	var err error
+	if iface == "Unit" {
+		props, err = p.conn.GetUnitProperties(p.name)
+	} else {
+		props, err = p.conn.GetUnitTypeProperties(p.name, iface)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't get unit's %s properties", iface)
+	}
+	p.props[iface] = props
+	return props, nil
+}
+
+// get returns a property of the unit from the given interface, where
+// iface is "Unit" or a type specific interface such as "Service"
+func (p *unitProperties) get(iface string, propName string) (interface{}, error) {
+	props, err := p.load(iface)
+	if err != nil {
+		return nil, errors.Wrapf(err, errGetPropertyMsg, propName)
+	}
+	val, ok := props[propName]
+	if !ok {
+		return nil, errors.Errorf(errGetPropertyMsg, propName)
+	}
+	return val, nil
+}
+
+func (p *unitProperties) getString(iface string, propName string) (string, error) {
+	val, err := p.get(iface, propName)
+	if err != nil {
+		return "", err
+	}
+	s, ok := val.(string)
+	if !ok {
+		return "", errors.Errorf(errConvertStringPropertyMsg, propName, val)
+	}
+	return s, nil
+}
+
+func (p *unitProperties) getUint64(iface string, propName string) (uint64, error) {
+	val, err := p.get(iface, propName)
+	if err != nil {
+		return 0, err
+	}
+	u, ok := val.(uint64)
+	if !ok {
+		return 0, errors.Errorf(errConvertUint64PropertyMsg, propName, val)
+	}
+	return u, nil
+}
+
+func (p *unitProperties) getUint32(iface string, propName string) (uint32, error) {
+	val, err := p.get(iface, propName)
+	if err != nil {
+		return 0, err
+	}
+	u, ok := val.(uint32)
+	if !ok {
+		return 0, errors.Errorf(errConvertUint32PropertyMsg, propName, val)
+	}
+	return u, nil
+}
+
+func (p *unitProperties) getInt32(iface string, propName string) (int32, error) {
+	val, err := p.get(iface, propName)
+	if err != nil {
+		return 0, err
+	}
+	i, ok := val.(int32)
+	if !ok {
+		return 0, errors.Errorf(errConvertInt32PropertyMsg, propName, val)
+	}
+	return i, nil
+}
+
+func (p *unitProperties) getBool(iface string, propName string) (bool, error) {
+	val, err := p.get(iface, propName)
+	if err != nil {
+		return false, err
+	}
+	b, ok := val.(bool)
+	if !ok {
+		return false, errors.Errorf(errConvertBoolPropertyMsg, propName, val)
+	}
+	return b, nil
+}
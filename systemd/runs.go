@@ -0,0 +1,95 @@
+package systemd
+
+import (
+	"sync"
+
+	"github.com/coreos/go-systemd/dbus"
+)
+
+// runHistory follows the runs of batch services, oneshot services and
+// services started by timers, which are best judged by how their runs went
+// rather than by their current state. systemd gives every run of a unit a
+// new invocation ID, which tells us whether the run we see is one we
+// already counted. Only the latest run is visible, so when a service runs
+// more than once between two scrapes the earlier runs are not seen.
+type runHistory struct {
+	mu       sync.Mutex
+	services map[string]*serviceRuns
+}
+
+// serviceRuns is what we know about the runs of a single service
+type serviceRuns struct {
+	invocationID string
+	duration     float64
+	result       string
+	lastSuccess  float64
+	successes    uint64
+	failures     uint64
+}
+
+// serviceRun describes the run of a service as read from systemd
+type serviceRun struct {
+	invocationID string
+	result       string
+	// start and exit time of the main process in microseconds since epoch
+	start uint64
+	exit  uint64
+}
+
+func newRunHistory() *runHistory {
+	return &runHistory{services: make(map[string]*serviceRuns)}
+}
+
+// observe records run of the service if it finished and was not counted
+// before, and returns a copy of the service's history. The run seen first is
+// not counted, as it may well have finished long before the exporter
+// started. ok is false as long as no finished run was seen.
+func (h *runHistory) observe(name string, run serviceRun) (runs serviceRuns, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	runsPtr, known := h.services[name]
+	if !known {
+		runsPtr = &serviceRuns{}
+		h.services[name] = runsPtr
+	}
+	// The exit timestamp still belongs to the previous run while the main
+	// process of the current one is running
+	finished := run.invocationID != "" && run.start > 0 && run.exit >= run.start
+	if finished && runsPtr.invocationID != run.invocationID {
+		runsPtr.invocationID = run.invocationID
+		runsPtr.duration = float64(run.exit-run.start) / 1e6
+		runsPtr.result = run.result
+		if run.result == "success" {
+			runsPtr.lastSuccess = float64(run.exit) / 1e6
+		}
+		if known {
+			if run.result == "success" {
+				runsPtr.successes++
+			} else {
+				runsPtr.failures++
+			}
+		}
+	}
+	if runsPtr.invocationID == "" {
+		return serviceRuns{}, false
+	}
+	return *runsPtr, true
+}
+
+// retain forgets about all services but the given units
+func (h *runHistory) retain(units []dbus.UnitStatus) {
+	names := make(map[string]bool, len(units))
+	for _, unit := range units {
+		names[unit.Name] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for name := range h.services {
+		if !names[name] {
+			delete(h.services, name)
+		}
+	}
+}
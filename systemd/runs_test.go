@@ -0,0 +1,95 @@
+package systemd
+
+import "testing"
+
+func TestRunHistoryObserve(t *testing.T) {
+	type observation struct {
+		run      serviceRun
+		wantOK   bool
+		wantRuns serviceRuns
+	}
+	tests := []struct {
+		name         string
+		observations []observation
+	}{
+		{
+			name: "run before the exporter started is not counted",
+			observations: []observation{
+				{
+					run:      serviceRun{invocationID: "a", result: "success", start: 1000000, exit: 3000000},
+					wantOK:   true,
+					wantRuns: serviceRuns{invocationID: "a", duration: 2, result: "success", lastSuccess: 3},
+				},
+				{
+					run:      serviceRun{invocationID: "a", result: "success", start: 1000000, exit: 3000000},
+					wantOK:   true,
+					wantRuns: serviceRuns{invocationID: "a", duration: 2, result: "success", lastSuccess: 3},
+				},
+				{
+					run:      serviceRun{invocationID: "b", result: "exit-code", start: 5000000, exit: 5500000},
+					wantOK:   true,
+					wantRuns: serviceRuns{invocationID: "b", duration: 0.5, result: "exit-code", lastSuccess: 3, failures: 1},
+				},
+				{
+					run:      serviceRun{invocationID: "c", result: "success", start: 7000000, exit: 8000000},
+					wantOK:   true,
+					wantRuns: serviceRuns{invocationID: "c", duration: 1, result: "success", lastSuccess: 8, successes: 1, failures: 1},
+				},
+			},
+		},
+		{
+			name: "never ran",
+			observations: []observation{
+				{run: serviceRun{}},
+				{run: serviceRun{}},
+			},
+		},
+		{
+			name: "first run seen while running is counted",
+			observations: []observation{
+				// The exit timestamp is from the run before
+				{run: serviceRun{invocationID: "a", result: "success", start: 5000000, exit: 3000000}},
+				{
+					run:      serviceRun{invocationID: "a", result: "success", start: 5000000, exit: 6000000},
+					wantOK:   true,
+					wantRuns: serviceRuns{invocationID: "a", duration: 1, result: "success", lastSuccess: 6, successes: 1},
+				},
+			},
+		},
+		{
+			name: "running again",
+			observations: []observation{
+				{
+					run:      serviceRun{invocationID: "a", result: "success", start: 1000000, exit: 2000000},
+					wantOK:   true,
+					wantRuns: serviceRuns{invocationID: "a", duration: 1, result: "success", lastSuccess: 2},
+				},
+				{
+					run:      serviceRun{invocationID: "b", result: "success", start: 4000000, exit: 2000000},
+					wantOK:   true,
+					wantRuns: serviceRuns{invocationID: "a", duration: 1, result: "success", lastSuccess: 2},
+				},
+				{
+					run:      serviceRun{invocationID: "b", result: "signal", start: 4000000, exit: 4000000},
+					wantOK:   true,
+					wantRuns: serviceRuns{invocationID: "b", duration: 0, result: "signal", lastSuccess: 2, failures: 1},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newRunHistory()
+			for i, o := range tt.observations {
+				runs, ok := h.observe("foo.service", o.run)
+				if ok != o.wantOK {
+					t.Fatalf("observation %d: observe() ok = %v, want %v", i, ok, o.wantOK)
+				}
+				if runs != o.wantRuns {
+					t.Errorf("observation %d: observe() = %+v, want %+v", i, runs, o.wantRuns)
+				}
+			}
+		})
+	}
+}
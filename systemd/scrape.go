@@ -0,0 +1,97 @@
+package systemd
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-systemd/dbus"
+)
+
+// scrape holds what the collectors of different units share during a single
+// scrape. Units are collected concurrently, so everything but activeStates
+// must only be touched with mu held until all units are collected.
+type scrape struct {
+	// activeStates holds the ActiveState of all units systemd has loaded,
+	// including filtered ones
+	activeStates map[string]string
+
+	mu sync.Mutex
+	// serviceResults holds the results of the services collected
+	serviceResults map[string]serviceResult
+	// timerServices maps the timers collected to the service they trigger
+	timerServices map[string]string
+	// lastStarts holds when the units collected last left the inactive
+	// state, in microseconds since epoch
+	lastStarts map[string]uint64
+	// pathUnits maps the path units collected to the unit they trigger
+	pathUnits map[string]string
+}
+
+// serviceResult is how the last run of a service went
+type serviceResult struct {
+	result string
+	status int32
+	// exitTime is when the main process exited in microseconds since epoch
+	exitTime uint64
+}
+
+func newScrape(units []dbus.UnitStatus) *scrape {
+	activeStates := make(map[string]string, len(units))
+	for _, unit := range units {
+		activeStates[unit.Name] = unit.ActiveState
+	}
+	return &scrape{
+		activeStates:   activeStates,
+		serviceResults: make(map[string]serviceResult),
+		timerServices:  make(map[string]string),
+		lastStarts:     make(map[string]uint64),
+		pathUnits:      make(map[string]string),
+	}
+}
+
+func (s *scrape) addServiceResult(name string, result serviceResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.serviceResults[name] = result
+}
+
+// addTimer records the service the timer triggers. Timers may also trigger
+// other kinds of units, which have no result and are left out.
+func (s *scrape) addTimer(name string, props *unitProperties) error {
+	triggered, err := props.getString("Timer", "Unit")
+	if err != nil {
+		return err
+	}
+	if !strings.HasSuffix(triggered, ".service") {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.timerServices[name] = triggered
+	return nil
+}
+
+// addLastStart records when the unit last left the inactive state, which is
+// when a path unit triggering it last did so
+func (s *scrape) addLastStart(name string, props *unitProperties) error {
+	lastStart, err := props.getUint64("Unit", "InactiveExitTimestamp")
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastStarts[name] = lastStart
+	return nil
+}
+
+func (s *scrape) addPath(name string, triggered string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pathUnits[name] = triggered
+}
@@ -1,11 +1,13 @@
 package systemd
 
 import (
+	"encoding/hex"
 	"fmt"
 	"math"
 	// Register pprof-over-http handlers
 	_ "net/http/pprof"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -25,17 +27,32 @@ var (
 	unitBlacklist         = kingpin.Flag("collector.unit-blacklist", "Regexp of systemd units to blacklist. Units must both match whitelist and not match blacklist to be included.").Default(".+\\.(device)").String()
 	systemdPrivate        = kingpin.Flag("collector.private", "Establish a private, direct connection to systemd without dbus.").Bool()
 	procPath              = kingpin.Flag("path.procfs", "procfs mountpoint.").Default(procfs.DefaultMountPoint).String()
-	enableRestartsMetrics = kingpin.Flag("collector.enable-restart-count", "Enables service restart count metrics. This feature only works with systemd 235 and above.").Bool()
+	enableRestartsMetrics = kingpin.Flag("collector.enable-restart-count", "Enables service restart count metrics even if the version of systemd could not be detected. They are enabled automatically on systemd 235 and above.").Bool()
 	enableFDMetrics       = kingpin.Flag("collector.enable-file-descriptor-size", "Enables file descriptor size metrics. Systemd Exporter needs access to /proc/X/fd for this to work.").Bool()
+	criticalChainMetrics  = kingpin.Flag("collector.enable-critical-chain", "Enables metrics about which units are on the critical chain of the boot, like systemd-analyze critical-chain shows.").Bool()
+	dbusAccounting        = kingpin.Flag("collector.enable-dbus-accounting", "Enables metrics from the resource accounting systemd reports over dbus, which needs no access to /sys/fs/cgroup.").Bool()
+	cgroupProcesses       = kingpin.Flag("collector.enable-cgroup-processes", "Aggregate process metrics over all processes in the control group of services, scopes and slices instead of only the main process of services.").Bool()
+	perCPUUnits           = kingpin.Flag("collector.per-cpu-units", "Regexp of systemd units to export per-CPU usage for, in addition to their total CPU usage. Only available on the legacy and hybrid cgroup hierarchies.").Default("").String()
+	resolveBlockDevices   = kingpin.Flag("collector.resolve-block-device-names", "Label unit block IO metrics with device names (e.g. sda) instead of major:minor device numbers.").Bool()
+	watchUnits            = kingpin.Flag("collector.watch-units", "Keep the list of units up to date from systemd's dbus signals instead of listing all units on every scrape.").Bool()
 )
 
 var unitStatesName = []string{"active", "activating", "deactivating", "inactive", "failed"}
 
+// automountResults are the values the Result property of automount units
+// can take
+var automountResults = []string{"success", "resources", "start-limit-hit", "mount-start-limit-hit", "unmounted"}
+
+// serviceResults are the values the Result property of services can take
+var serviceResults = []string{"success", "protocol", "timeout", "exit-code", "signal", "core-dump", "watchdog", "start-limit-hit", "resources", "oom-kill", "exec-condition"}
+
 var (
 	errGetPropertyMsg           = "couldn't get unit's %s property"
 	errConvertUint64PropertyMsg = "couldn't convert unit's %s property %v to uint64"
 	errConvertUint32PropertyMsg = "couldn't convert unit's %s property %v to uint32"
+	errConvertInt32PropertyMsg  = "couldn't convert unit's %s property %v to int32"
 	errConvertStringPropertyMsg = "couldn't convert unit's %s property %v to string"
+	errConvertBoolPropertyMsg   = "couldn't convert unit's %s property %v to bool"
 	errUnitMetricsMsg           = "couldn't get unit's metrics: %s"
 	errControlGroupReadMsg      = "failed to read %s from control group"
 	infoUnitNoHandler           = "no unit type handler for %s"
@@ -46,10 +63,40 @@ type Collector struct {
 	unitState                     *prometheus.Desc
 	unitInfo                      *prometheus.Desc
 	unitStartTimeDesc             *prometheus.Desc
+	unitActivationTimeDesc        *prometheus.Desc
+	unitDeactivationTimeDesc      *prometheus.Desc
+	unitCriticalChainDesc         *prometheus.Desc
+	unitCriticalChainOffsetDesc   *prometheus.Desc
 	unitTasksCurrentDesc          *prometheus.Desc
 	unitTasksMaxDesc              *prometheus.Desc
 	nRestartsDesc                 *prometheus.Desc
+	serviceResultDesc             *prometheus.Desc
+	serviceExecMainStatusDesc     *prometheus.Desc
+	serviceExecMainCodeDesc       *prometheus.Desc
+	serviceFailedTimeDesc         *prometheus.Desc
+	serviceRunDurationDesc        *prometheus.Desc
+	serviceRunResultDesc          *prometheus.Desc
+	serviceLastSuccessDesc        *prometheus.Desc
+	serviceRunSuccessesDesc       *prometheus.Desc
+	serviceRunFailuresDesc        *prometheus.Desc
 	timerLastTriggerDesc          *prometheus.Desc
+	timerNextTriggerDesc          *prometheus.Desc
+	timerInfoDesc                 *prometheus.Desc
+	timerAccuracyDesc             *prometheus.Desc
+	timerRandomizedDelayDesc      *prometheus.Desc
+	timerLastTriggerDelayDesc     *prometheus.Desc
+	timerLastResultDesc           *prometheus.Desc
+	timerLastExitStatusDesc       *prometheus.Desc
+	timerLastExitTimeDesc         *prometheus.Desc
+	pathInfoDesc                  *prometheus.Desc
+	pathResultDesc                *prometheus.Desc
+	pathLastTriggerDesc           *prometheus.Desc
+	pathTriggersDesc              *prometheus.Desc
+	automountInfoDesc             *prometheus.Desc
+	automountTimeoutIdleDesc      *prometheus.Desc
+	automountResultDesc           *prometheus.Desc
+	automountMountedDesc          *prometheus.Desc
+	targetDependenciesDesc        *prometheus.Desc
 	socketAcceptedConnectionsDesc *prometheus.Desc
 	socketCurrentConnectionsDesc  *prometheus.Desc
 	socketRefusedConnectionsDesc  *prometheus.Desc
@@ -60,9 +107,64 @@ type Collector struct {
 	vsize                         *prometheus.Desc
 	maxVsize                      *prometheus.Desc
 	rss                           *prometheus.Desc
+	unitProcessesDesc             *prometheus.Desc
+	versionDesc                   *prometheus.Desc
+	systemStateDesc               *prometheus.Desc
+	failedUnitsDesc               *prometheus.Desc
+	jobsDesc                      *prometheus.Desc
+	installedJobsDesc             *prometheus.Desc
+	failedJobsDesc                *prometheus.Desc
+	managerInfoDesc               *prometheus.Desc
+	defaultAccountingDesc         *prometheus.Desc
+	reloadsDesc                   *prometheus.Desc
+	bootDurationDesc              *prometheus.Desc
+	kernelTimestampDesc           *prometheus.Desc
+	dbusConnectedDesc             *prometheus.Desc
+	dbusReconnectsDesc            *prometheus.Desc
+	dbusConnectErrorsDesc         *prometheus.Desc
+	unitStateTransitionsDesc      *prometheus.Desc
+	unitCPUCoreTotal              *prometheus.Desc
+	unitCPUPeriodsDesc            *prometheus.Desc
+	unitCPUThrottledPeriodsDesc   *prometheus.Desc
+	unitCPUThrottledDesc          *prometheus.Desc
+	unitCPUQuotaDesc              *prometheus.Desc
+	unitCPUQuotaPeriodDesc        *prometheus.Desc
+	unitMemoryUsageDesc           *prometheus.Desc
+	unitMemoryPeakDesc            *prometheus.Desc
+	unitMemorySwapUsageDesc       *prometheus.Desc
+	unitMemoryStatDesc            *prometheus.Desc
+	unitMemoryEventsDesc          *prometheus.Desc
+	unitMemoryLimitDesc           *prometheus.Desc
+	unitIOReadBytesDesc           *prometheus.Desc
+	unitIOWriteBytesDesc          *prometheus.Desc
+	unitIOReadsDesc               *prometheus.Desc
+	unitIOWritesDesc              *prometheus.Desc
+	unitPressureWaitingDesc       *prometheus.Desc
+	unitPressureStalledDesc       *prometheus.Desc
+	accountingMemoryDesc          *prometheus.Desc
+	accountingCPUDesc             *prometheus.Desc
+	accountingTasksDesc           *prometheus.Desc
+	accountingIOReadBytesDesc     *prometheus.Desc
+	accountingIOWriteBytesDesc    *prometheus.Desc
+	accountingIOReadsDesc         *prometheus.Desc
+	accountingIOWritesDesc        *prometheus.Desc
+	accountingIPIngressBytesDesc  *prometheus.Desc
+	accountingIPEgressBytesDesc   *prometheus.Desc
+	accountingIPIngressPktsDesc   *prometheus.Desc
+	accountingIPEgressPktsDesc    *prometheus.Desc
+
+	conn    *systemdConn
+	watcher *unitWatcher
+	version *systemdVersion
+	reloads *reloadCounter
+	chain   *criticalChain
+	timers  *timerSchedules
+	runs    *runHistory
+	paths   *pathTriggers
 
 	unitWhitelistPattern *regexp.Regexp
 	unitBlacklistPattern *regexp.Regexp
+	perCPUUnitsPattern   *regexp.Regexp
 }
 
 // NewCollector returns a new Collector exposing systemd statistics.
@@ -73,6 +175,11 @@ func NewCollector(logger log.Logger) (*Collector, error) {
 		prometheus.BuildFQName(namespace, "", "unit_state"),
 		"Systemd unit", []string{"name", "type", "state"}, nil,
 	)
+	unitStateTransitionsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_state_transitions_total"),
+		"Number of times a unit changed its active state, requires --collector.watch-units.",
+		[]string{"name", "type", "from", "to"}, nil,
+	)
 	// TODO think about if we want to have 1) one unit_info metric which has all possible labels
 	// for all possible unit type variables (at least, the relatively static ones that we care
 	// about such as type, generated-vs-real-unit, etc). Cons: a) huge waste since all these labels
@@ -94,6 +201,26 @@ func NewCollector(logger log.Logger) (*Collector, error) {
 		"Start time of the unit since unix epoch in seconds.",
 		[]string{"name", "type"}, nil,
 	)
+	unitActivationTimeDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_activation_duration_seconds"),
+		"Time in seconds the unit took to become active the last time it was started",
+		[]string{"name", "type"}, nil,
+	)
+	unitDeactivationTimeDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_deactivation_duration_seconds"),
+		"Time in seconds the unit took to become inactive the last time it was stopped",
+		[]string{"name", "type"}, nil,
+	)
+	unitCriticalChainDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_on_critical_chain"),
+		"Whether the unit is on the critical chain of the current boot",
+		[]string{"name", "type"}, nil,
+	)
+	unitCriticalChainOffsetDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_critical_chain_offset_seconds"),
+		"Time in seconds after userspace started that a unit on the critical chain started",
+		[]string{"name", "type"}, nil,
+	)
 	unitTasksCurrentDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "unit_tasks_current"),
 		"Current number of tasks per Systemd unit",
@@ -107,9 +234,105 @@ func NewCollector(logger log.Logger) (*Collector, error) {
 	nRestartsDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "service_restart_total"),
 		"Service unit count of Restart triggers", []string{"state"}, nil)
+	serviceResultDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "service", "result"),
+		"Result of the service's last run, success unless it failed.",
+		[]string{"name", "result"}, nil,
+	)
+	serviceExecMainStatusDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "service", "exec_main_status"),
+		"Exit status or terminating signal of the service's main process.",
+		[]string{"name"}, nil,
+	)
+	serviceExecMainCodeDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "service", "exec_main_code"),
+		"How the service's main process ended, 1 exited, 2 killed or 3 dumped core (see waitid(2) CLD_*), 0 if it is still running.",
+		[]string{"name"}, nil,
+	)
+	serviceFailedTimeDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "service", "failed_timestamp_seconds"),
+		"Seconds since epoch the service entered failed state, only reported while it is failed.",
+		[]string{"name"}, nil,
+	)
+	serviceRunDurationDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "service", "last_run_duration_seconds"),
+		"Time in seconds the main process of the last finished run of a batch service ran.",
+		[]string{"name"}, nil,
+	)
+	serviceRunResultDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "service", "last_run_result"),
+		"Result of the last finished run of a batch service.",
+		[]string{"name", "result"}, nil,
+	)
+	serviceLastSuccessDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "service", "last_success_timestamp_seconds"),
+		"Seconds since epoch the last successful run of a batch service finished.",
+		[]string{"name"}, nil,
+	)
+	serviceRunSuccessesDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "service", "run_successes_total"),
+		"Number of successful runs of a batch service seen since the exporter started.",
+		[]string{"name"}, nil,
+	)
+	serviceRunFailuresDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "service", "run_failures_total"),
+		"Number of failed runs of a batch service seen since the exporter started.",
+		[]string{"name"}, nil,
+	)
 	timerLastTriggerDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "timer_last_trigger_seconds"),
 		"Seconds since epoch of last trigger.", []string{"name"}, nil)
+	timerNextTriggerDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "timer_next_trigger_seconds"),
+		"Seconds since epoch of the next trigger, the earlier of the calendar and the monotonic schedule.", []string{"name"}, nil)
+	timerInfoDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "timer_info"),
+		"Schedule of the timer.", []string{"name", "calendar", "monotonic", "persistent"}, nil)
+	timerAccuracyDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "timer_accuracy_seconds"),
+		"Accuracy the timer is allowed to fire with (AccuracySec=).", []string{"name"}, nil)
+	timerRandomizedDelayDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "timer_randomized_delay_seconds"),
+		"Maximum random delay added to the timer's schedule (RandomizedDelaySec=).", []string{"name"}, nil)
+	timerLastTriggerDelayDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "timer_last_trigger_delay_seconds"),
+		"Seconds the last trigger was late compared to the schedule observed before it.", []string{"name"}, nil)
+	timerLastResultDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "timer_last_result"),
+		"Result of the last run of the service triggered by the timer.", []string{"name", "unit", "result"}, nil)
+	timerLastExitStatusDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "timer_last_exit_status"),
+		"Exit status of the main process of the service triggered by the timer.", []string{"name", "unit"}, nil)
+	timerLastExitTimeDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "timer_last_exit_timestamp_seconds"),
+		"Seconds since epoch the main process of the service triggered by the timer exited.", []string{"name", "unit"}, nil)
+	pathInfoDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "path_info"),
+		"Paths watched by the path unit and the unit it triggers.", []string{"name", "unit", "paths"}, nil)
+	pathResultDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "path_result"),
+		"Result of the path unit, success unless it failed.", []string{"name", "result"}, nil)
+	pathLastTriggerDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "path_last_trigger_seconds"),
+		"Seconds since epoch the unit triggered by the path unit was last started.", []string{"name"}, nil)
+	pathTriggersDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "path_triggers_total"),
+		"Number of times the unit triggered by the path unit was started since the exporter started.", []string{"name"}, nil)
+	automountInfoDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "automount_info"),
+		"Mount point of the automount unit and the mode directories are created with.", []string{"name", "where", "directory_mode"}, nil)
+	automountTimeoutIdleDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "automount_timeout_idle_seconds"),
+		"Seconds of inactivity after which the automount unit unmounts, 0 if it never does.", []string{"name"}, nil)
+	automountResultDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "automount_result"),
+		"Result of the automount unit, success unless it failed.", []string{"name", "result"}, nil)
+	automountMountedDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "automount_mounted"),
+		"Whether the mount unit backing the automount unit is active.", []string{"name", "mount"}, nil)
+	targetDependenciesDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "target_dependencies"),
+		"Number of units the target depends on, by kind of dependency and their active state.", []string{"name", "dependency", "state"}, nil)
 	socketAcceptedConnectionsDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "socket_accepted_connections_total"),
 		"Total number of accepted socket connections", []string{"name"}, nil)
@@ -133,6 +356,153 @@ func NewCollector(logger log.Logger) (*Collector, error) {
 		"Unit CPU time in seconds",
 		[]string{"name", "type", "mode"}, nil,
 	)
+	// The per-core breakdown is a separate metric, only exported for the
+	// units selected with --collector.per-cpu-units, to keep cardinality in check
+	unitCPUCoreTotal := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_cpu_core_seconds_total"),
+		"Unit CPU time in seconds, per CPU core",
+		[]string{"name", "type", "mode", "cpu"}, nil,
+	)
+	unitCPUPeriodsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_cpu_periods_total"),
+		"Number of CPU bandwidth enforcement periods that elapsed for the unit's control group",
+		[]string{"name", "type"}, nil,
+	)
+	unitCPUThrottledPeriodsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_cpu_throttled_periods_total"),
+		"Number of CPU bandwidth enforcement periods in which the unit's control group was throttled",
+		[]string{"name", "type"}, nil,
+	)
+	unitCPUThrottledDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_cpu_throttled_seconds_total"),
+		"Total time in seconds the tasks of the unit's control group were throttled",
+		[]string{"name", "type"}, nil,
+	)
+	unitCPUQuotaDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_cpu_quota_seconds"),
+		"CPU time in seconds the unit may use per second of wall clock time (CPUQuota=), not reported if unlimited",
+		[]string{"name", "type"}, nil,
+	)
+	unitCPUQuotaPeriodDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_cpu_quota_period_seconds"),
+		"Period in seconds over which the unit's CPU quota is enforced (CPUQuotaPeriodSec=), not reported if left at the kernel default",
+		[]string{"name", "type"}, nil,
+	)
+	unitMemoryUsageDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_memory_usage_bytes"),
+		"Memory used by the unit's control group in bytes, including page cache",
+		[]string{"name", "type"}, nil,
+	)
+	unitMemoryPeakDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_memory_peak_bytes"),
+		"Highest memory usage recorded for the unit's control group in bytes",
+		[]string{"name", "type"}, nil,
+	)
+	unitMemorySwapUsageDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_memory_swap_usage_bytes"),
+		"Swap used by the unit's control group in bytes",
+		[]string{"name", "type"}, nil,
+	)
+	unitMemoryStatDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_memory_stat_bytes"),
+		"Memory used by the unit's control group in bytes, by kind of memory",
+		[]string{"name", "type", "stat"}, nil,
+	)
+	unitMemoryEventsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_memory_events_total"),
+		"Number of times the unit's control group hit a memory limit or the OOM killer, by event",
+		[]string{"name", "type", "event"}, nil,
+	)
+	unitMemoryLimitDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_memory_limit_bytes"),
+		"Memory limits configured for the unit in bytes, unlimited ones are not reported",
+		[]string{"name", "type", "limit"}, nil,
+	)
+	unitIOReadBytesDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_io_read_bytes_total"),
+		"Bytes read from a block device by the unit's control group",
+		[]string{"name", "type", "device"}, nil,
+	)
+	unitIOWriteBytesDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_io_write_bytes_total"),
+		"Bytes written to a block device by the unit's control group",
+		[]string{"name", "type", "device"}, nil,
+	)
+	unitIOReadsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_io_reads_total"),
+		"Read operations issued to a block device by the unit's control group",
+		[]string{"name", "type", "device"}, nil,
+	)
+	unitIOWritesDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_io_writes_total"),
+		"Write operations issued to a block device by the unit's control group",
+		[]string{"name", "type", "device"}, nil,
+	)
+	unitPressureWaitingDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_pressure_waiting_seconds_total"),
+		"Total time in seconds at least one task of the unit was stalled waiting on a resource (PSI some)",
+		[]string{"name", "type", "resource"}, nil,
+	)
+	unitPressureStalledDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_pressure_stalled_seconds_total"),
+		"Total time in seconds all non-idle tasks of the unit were stalled on a resource at once (PSI full)",
+		[]string{"name", "type", "resource"}, nil,
+	)
+	accountingMemoryDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_accounting_memory_bytes"),
+		"Memory used by the unit in bytes, as accounted by systemd (MemoryCurrent)",
+		[]string{"name", "type"}, nil,
+	)
+	accountingCPUDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_accounting_cpu_seconds_total"),
+		"CPU time used by the unit in seconds, as accounted by systemd (CPUUsageNSec)",
+		[]string{"name", "type"}, nil,
+	)
+	accountingTasksDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_accounting_tasks"),
+		"Number of tasks in the unit, as accounted by systemd (TasksCurrent)",
+		[]string{"name", "type"}, nil,
+	)
+	accountingIOReadBytesDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_accounting_io_read_bytes_total"),
+		"Bytes read from block devices by the unit, as accounted by systemd (IOReadBytes)",
+		[]string{"name", "type"}, nil,
+	)
+	accountingIOWriteBytesDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_accounting_io_write_bytes_total"),
+		"Bytes written to block devices by the unit, as accounted by systemd (IOWriteBytes)",
+		[]string{"name", "type"}, nil,
+	)
+	accountingIOReadsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_accounting_io_reads_total"),
+		"Read operations issued to block devices by the unit, as accounted by systemd (IOReadOperations)",
+		[]string{"name", "type"}, nil,
+	)
+	accountingIOWritesDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_accounting_io_writes_total"),
+		"Write operations issued to block devices by the unit, as accounted by systemd (IOWriteOperations)",
+		[]string{"name", "type"}, nil,
+	)
+	accountingIPIngressBytesDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_accounting_ip_ingress_bytes_total"),
+		"IP bytes received by the unit, as accounted by systemd (IPIngressBytes)",
+		[]string{"name", "type"}, nil,
+	)
+	accountingIPEgressBytesDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_accounting_ip_egress_bytes_total"),
+		"IP bytes sent by the unit, as accounted by systemd (IPEgressBytes)",
+		[]string{"name", "type"}, nil,
+	)
+	accountingIPIngressPktsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_accounting_ip_ingress_packets_total"),
+		"IP packets received by the unit, as accounted by systemd (IPIngressPackets)",
+		[]string{"name", "type"}, nil,
+	)
+	accountingIPEgressPktsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_accounting_ip_egress_packets_total"),
+		"IP packets sent by the unit, as accounted by systemd (IPEgressPackets)",
+		[]string{"name", "type"}, nil,
+	)
 
 	openFDs := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "process_open_fds"),
@@ -162,18 +532,147 @@ func NewCollector(logger log.Logger) (*Collector, error) {
 		"Resident memory size in bytes.",
 		[]string{"name"}, nil,
 	)
+	unitProcessesDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unit_processes"),
+		"Number of processes in the unit's control group",
+		[]string{"name", "type"}, nil,
+	)
+	dbusConnectedDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "exporter", "dbus_connected"),
+		"Whether the exporter currently holds a connection to systemd.",
+		nil, nil,
+	)
+	dbusReconnectsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "exporter", "dbus_reconnects_total"),
+		"Number of times the connection to systemd was re-established after it broke.",
+		nil, nil,
+	)
+	dbusConnectErrorsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "exporter", "dbus_connect_errors_total"),
+		"Number of failed attempts to connect to systemd.",
+		nil, nil,
+	)
+	versionDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "version"),
+		"Detected systemd version",
+		[]string{"version"}, nil,
+	)
+	systemStateDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "manager", "system_state"),
+		"Overall state of the system as reported by systemctl is-system-running",
+		[]string{"state"}, nil,
+	)
+	failedUnitsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "manager", "failed_units"),
+		"Number of units in failed state",
+		nil, nil,
+	)
+	jobsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "manager", "jobs"),
+		"Number of jobs currently queued",
+		nil, nil,
+	)
+	installedJobsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "manager", "installed_jobs_total"),
+		"Number of jobs installed since systemd started",
+		nil, nil,
+	)
+	failedJobsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "manager", "failed_jobs_total"),
+		"Number of jobs that failed since systemd started",
+		nil, nil,
+	)
+	managerInfoDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "manager", "info"),
+		"Information about the systemd manager",
+		[]string{"virtualization", "architecture", "features"}, nil,
+	)
+	defaultAccountingDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "manager", "default_accounting_enabled"),
+		"Whether accounting of a resource is enabled for units by default",
+		[]string{"resource"}, nil,
+	)
+	reloadsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "manager", "reloads_total"),
+		"Number of daemon-reloads observed since the exporter started",
+		nil, nil,
+	)
+	bootDurationDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "boot", "duration_seconds"),
+		"Time in seconds spent in each phase of the last boot, as reported by systemd-analyze time",
+		[]string{"phase"}, nil,
+	)
+	kernelTimestampDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "boot", "kernel_timestamp_seconds"),
+		"Time the kernel started booting as a unix timestamp",
+		nil, nil,
+	)
 	unitWhitelistPattern := regexp.MustCompile(fmt.Sprintf("^(?:%s)$", *unitWhitelist))
 	unitBlacklistPattern := regexp.MustCompile(fmt.Sprintf("^(?:%s)$", *unitBlacklist))
+	var perCPUUnitsPattern *regexp.Regexp
+	if *perCPUUnits != "" {
+		perCPUUnitsPattern = regexp.MustCompile(fmt.Sprintf("^(?:%s)$", *perCPUUnits))
+	}
+
+	conn := newSystemdConn(logger)
+	var watcher *unitWatcher
+	if *watchUnits {
+		watcher = newUnitWatcher()
+		conn.subscribe(watcher)
+	}
+	reloads := &reloadCounter{}
+	conn.subscribe(reloads)
+	version := &systemdVersion{}
+	conn.subscribe(version)
+	// Connect right away, so that the systemd version is known by the
+	// first scrape
+	if _, err := conn.get(); err != nil {
+		logger.Warnf("couldn't connect to systemd: %s", err)
+	}
+	var chain *criticalChain
+	if *criticalChainMetrics {
+		chain = newCriticalChain()
+	}
 
 	return &Collector{
 		logger:                        logger,
 		unitState:                     unitState,
 		unitInfo:                      unitInfo,
 		unitStartTimeDesc:             unitStartTimeDesc,
+		unitActivationTimeDesc:        unitActivationTimeDesc,
+		unitDeactivationTimeDesc:      unitDeactivationTimeDesc,
+		unitCriticalChainDesc:         unitCriticalChainDesc,
+		unitCriticalChainOffsetDesc:   unitCriticalChainOffsetDesc,
 		unitTasksCurrentDesc:          unitTasksCurrentDesc,
 		unitTasksMaxDesc:              unitTasksMaxDesc,
 		nRestartsDesc:                 nRestartsDesc,
+		serviceResultDesc:             serviceResultDesc,
+		serviceExecMainStatusDesc:     serviceExecMainStatusDesc,
+		serviceExecMainCodeDesc:       serviceExecMainCodeDesc,
+		serviceFailedTimeDesc:         serviceFailedTimeDesc,
+		serviceRunDurationDesc:        serviceRunDurationDesc,
+		serviceRunResultDesc:          serviceRunResultDesc,
+		serviceLastSuccessDesc:        serviceLastSuccessDesc,
+		serviceRunSuccessesDesc:       serviceRunSuccessesDesc,
+		serviceRunFailuresDesc:        serviceRunFailuresDesc,
 		timerLastTriggerDesc:          timerLastTriggerDesc,
+		timerNextTriggerDesc:          timerNextTriggerDesc,
+		timerInfoDesc:                 timerInfoDesc,
+		timerAccuracyDesc:             timerAccuracyDesc,
+		timerRandomizedDelayDesc:      timerRandomizedDelayDesc,
+		timerLastTriggerDelayDesc:     timerLastTriggerDelayDesc,
+		timerLastResultDesc:           timerLastResultDesc,
+		timerLastExitStatusDesc:       timerLastExitStatusDesc,
+		timerLastExitTimeDesc:         timerLastExitTimeDesc,
+		pathInfoDesc:                  pathInfoDesc,
+		pathResultDesc:                pathResultDesc,
+		pathLastTriggerDesc:           pathLastTriggerDesc,
+		pathTriggersDesc:              pathTriggersDesc,
+		automountInfoDesc:             automountInfoDesc,
+		automountTimeoutIdleDesc:      automountTimeoutIdleDesc,
+		automountResultDesc:           automountResultDesc,
+		automountMountedDesc:          automountMountedDesc,
+		targetDependenciesDesc:        targetDependenciesDesc,
 		socketAcceptedConnectionsDesc: socketAcceptedConnectionsDesc,
 		socketCurrentConnectionsDesc:  socketCurrentConnectionsDesc,
 		socketRefusedConnectionsDesc:  socketRefusedConnectionsDesc,
@@ -184,8 +683,62 @@ func NewCollector(logger log.Logger) (*Collector, error) {
 		vsize:                         vsize,
 		maxVsize:                      maxVsize,
 		rss:                           rss,
+		unitProcessesDesc:             unitProcessesDesc,
+		versionDesc:                   versionDesc,
+		systemStateDesc:               systemStateDesc,
+		failedUnitsDesc:               failedUnitsDesc,
+		jobsDesc:                      jobsDesc,
+		installedJobsDesc:             installedJobsDesc,
+		failedJobsDesc:                failedJobsDesc,
+		managerInfoDesc:               managerInfoDesc,
+		defaultAccountingDesc:         defaultAccountingDesc,
+		reloadsDesc:                   reloadsDesc,
+		bootDurationDesc:              bootDurationDesc,
+		kernelTimestampDesc:           kernelTimestampDesc,
+		dbusConnectedDesc:             dbusConnectedDesc,
+		dbusReconnectsDesc:            dbusReconnectsDesc,
+		dbusConnectErrorsDesc:         dbusConnectErrorsDesc,
+		unitStateTransitionsDesc:      unitStateTransitionsDesc,
+		unitCPUCoreTotal:              unitCPUCoreTotal,
+		unitCPUPeriodsDesc:            unitCPUPeriodsDesc,
+		unitCPUThrottledPeriodsDesc:   unitCPUThrottledPeriodsDesc,
+		unitCPUThrottledDesc:          unitCPUThrottledDesc,
+		unitCPUQuotaDesc:              unitCPUQuotaDesc,
+		unitCPUQuotaPeriodDesc:        unitCPUQuotaPeriodDesc,
+		unitMemoryUsageDesc:           unitMemoryUsageDesc,
+		unitMemoryPeakDesc:            unitMemoryPeakDesc,
+		unitMemorySwapUsageDesc:       unitMemorySwapUsageDesc,
+		unitMemoryStatDesc:            unitMemoryStatDesc,
+		unitMemoryEventsDesc:          unitMemoryEventsDesc,
+		unitMemoryLimitDesc:           unitMemoryLimitDesc,
+		unitIOReadBytesDesc:           unitIOReadBytesDesc,
+		unitIOWriteBytesDesc:          unitIOWriteBytesDesc,
+		unitIOReadsDesc:               unitIOReadsDesc,
+		unitIOWritesDesc:              unitIOWritesDesc,
+		unitPressureWaitingDesc:       unitPressureWaitingDesc,
+		unitPressureStalledDesc:       unitPressureStalledDesc,
+		accountingMemoryDesc:          accountingMemoryDesc,
+		accountingCPUDesc:             accountingCPUDesc,
+		accountingTasksDesc:           accountingTasksDesc,
+		accountingIOReadBytesDesc:     accountingIOReadBytesDesc,
+		accountingIOWriteBytesDesc:    accountingIOWriteBytesDesc,
+		accountingIOReadsDesc:         accountingIOReadsDesc,
+		accountingIOWritesDesc:        accountingIOWritesDesc,
+		accountingIPIngressBytesDesc:  accountingIPIngressBytesDesc,
+		accountingIPEgressBytesDesc:   accountingIPEgressBytesDesc,
+		accountingIPIngressPktsDesc:   accountingIPIngressPktsDesc,
+		accountingIPEgressPktsDesc:    accountingIPEgressPktsDesc,
+		conn:                          conn,
+		watcher:                       watcher,
+		version:                       version,
+		reloads:                       reloads,
+		chain:                         chain,
+		timers:                        newTimerSchedules(),
+		runs:                          newRunHistory(),
+		paths:                         newPathTriggers(),
 		unitWhitelistPattern:          unitWhitelistPattern,
 		unitBlacklistPattern:          unitBlacklistPattern,
+		perCPUUnitsPattern:            perCPUUnitsPattern,
 	}, nil
 }
 
@@ -202,10 +755,40 @@ func (c *Collector) Describe(desc chan<- *prometheus.Desc) {
 	desc <- c.unitState
 	desc <- c.unitInfo
 	desc <- c.unitStartTimeDesc
+	desc <- c.unitActivationTimeDesc
+	desc <- c.unitDeactivationTimeDesc
+	desc <- c.unitCriticalChainDesc
+	desc <- c.unitCriticalChainOffsetDesc
 	desc <- c.unitTasksCurrentDesc
 	desc <- c.unitTasksMaxDesc
 	desc <- c.nRestartsDesc
+	desc <- c.serviceResultDesc
+	desc <- c.serviceExecMainStatusDesc
+	desc <- c.serviceExecMainCodeDesc
+	desc <- c.serviceFailedTimeDesc
+	desc <- c.serviceRunDurationDesc
+	desc <- c.serviceRunResultDesc
+	desc <- c.serviceLastSuccessDesc
+	desc <- c.serviceRunSuccessesDesc
+	desc <- c.serviceRunFailuresDesc
 	desc <- c.timerLastTriggerDesc
+	desc <- c.timerNextTriggerDesc
+	desc <- c.timerInfoDesc
+	desc <- c.timerAccuracyDesc
+	desc <- c.timerRandomizedDelayDesc
+	desc <- c.timerLastTriggerDelayDesc
+	desc <- c.timerLastResultDesc
+	desc <- c.timerLastExitStatusDesc
+	desc <- c.timerLastExitTimeDesc
+	desc <- c.pathInfoDesc
+	desc <- c.pathResultDesc
+	desc <- c.pathLastTriggerDesc
+	desc <- c.pathTriggersDesc
+	desc <- c.automountInfoDesc
+	desc <- c.automountTimeoutIdleDesc
+	desc <- c.automountResultDesc
+	desc <- c.automountMountedDesc
+	desc <- c.targetDependenciesDesc
 	desc <- c.socketAcceptedConnectionsDesc
 	desc <- c.socketCurrentConnectionsDesc
 	desc <- c.socketRefusedConnectionsDesc
@@ -215,6 +798,51 @@ func (c *Collector) Describe(desc chan<- *prometheus.Desc) {
 	desc <- c.vsize
 	desc <- c.maxVsize
 	desc <- c.rss
+	desc <- c.unitProcessesDesc
+	desc <- c.versionDesc
+	desc <- c.systemStateDesc
+	desc <- c.failedUnitsDesc
+	desc <- c.jobsDesc
+	desc <- c.installedJobsDesc
+	desc <- c.failedJobsDesc
+	desc <- c.managerInfoDesc
+	desc <- c.defaultAccountingDesc
+	desc <- c.reloadsDesc
+	desc <- c.bootDurationDesc
+	desc <- c.kernelTimestampDesc
+	desc <- c.dbusConnectedDesc
+	desc <- c.dbusReconnectsDesc
+	desc <- c.dbusConnectErrorsDesc
+	desc <- c.unitStateTransitionsDesc
+	desc <- c.unitCPUCoreTotal
+	desc <- c.unitCPUPeriodsDesc
+	desc <- c.unitCPUThrottledPeriodsDesc
+	desc <- c.unitCPUThrottledDesc
+	desc <- c.unitCPUQuotaDesc
+	desc <- c.unitCPUQuotaPeriodDesc
+	desc <- c.unitMemoryUsageDesc
+	desc <- c.unitMemoryPeakDesc
+	desc <- c.unitMemorySwapUsageDesc
+	desc <- c.unitMemoryStatDesc
+	desc <- c.unitMemoryEventsDesc
+	desc <- c.unitMemoryLimitDesc
+	desc <- c.unitIOReadBytesDesc
+	desc <- c.unitIOWriteBytesDesc
+	desc <- c.unitIOReadsDesc
+	desc <- c.unitIOWritesDesc
+	desc <- c.unitPressureWaitingDesc
+	desc <- c.unitPressureStalledDesc
+	desc <- c.accountingMemoryDesc
+	desc <- c.accountingCPUDesc
+	desc <- c.accountingTasksDesc
+	desc <- c.accountingIOReadBytesDesc
+	desc <- c.accountingIOWriteBytesDesc
+	desc <- c.accountingIOReadsDesc
+	desc <- c.accountingIOWritesDesc
+	desc <- c.accountingIPIngressBytesDesc
+	desc <- c.accountingIPEgressBytesDesc
+	desc <- c.accountingIPIngressPktsDesc
+	desc <- c.accountingIPEgressPktsDesc
 }
 
 func parseUnitType(unit dbus.UnitStatus) string {
@@ -224,27 +852,58 @@ func parseUnitType(unit dbus.UnitStatus) string {
 
 func (c *Collector) collect(ch chan<- prometheus.Metric) error {
 	begin := time.Now()
-	conn, err := c.newDbus()
+	conn, err := c.conn.get()
+	c.collectConnectionMetrics(ch)
 	if err != nil {
 		return errors.Wrapf(err, "couldn't get dbus connection")
 	}
-	defer conn.Close()
 
-	allUnits, err := conn.ListUnits()
+	if version, _ := c.version.get(); version != "" {
+		ch <- prometheus.MustNewConstMetric(
+			c.versionDesc, prometheus.GaugeValue, 1.0, version)
+	}
+
+	managerProps, err := c.conn.managerProperties(conn)
+	if err != nil {
+		c.logger.Warnf("couldn't get systemd manager metrics: %s", err)
+	}
+	c.collectManagerMetrics(ch, managerProps)
+
+	var allUnits []dbus.UnitStatus
+	if c.watcher != nil {
+		allUnits, err = c.watcher.list(conn)
+	} else {
+		allUnits, err = conn.ListUnits()
+	}
 	if err != nil {
+		if isConnectionError(err) {
+			c.conn.invalidate(conn)
+		}
 		return errors.Wrap(err, "could not get list of systemd units from dbus")
 	}
 
 	c.logger.Debugf("systemd ListUnits took %f", time.Since(begin).Seconds())
+
+	if c.chain != nil && managerProps != nil {
+		begin = time.Now()
+		err = c.chain.update(c.conn, conn, managerProps, allUnits)
+		if err != nil {
+			c.logger.Warnf("couldn't determine critical chain: %s", err)
+		}
+		c.logger.Debugf("systemd critical chain took %f", time.Since(begin).Seconds())
+	}
+
 	begin = time.Now()
 	units := filterUnits(allUnits, c.unitWhitelistPattern, c.unitBlacklistPattern)
 	c.logger.Debugf("systemd filterUnits took %f", time.Since(begin).Seconds())
 
+	s := newScrape(allUnits)
+
 	var wg sync.WaitGroup
 	wg.Add(len(units))
 	for _, unit := range units {
 		go func(unit dbus.UnitStatus) {
-			err := c.collectUnit(conn, ch, unit)
+			err := c.collectUnit(conn, ch, unit, s)
 			if err != nil {
 				c.logger.Warnf(errUnitMetricsMsg, err)
 			}
@@ -253,98 +912,282 @@ func (c *Collector) collect(ch chan<- prometheus.Metric) error {
 	}
 
 	wg.Wait()
+	c.collectTimerResultMetrics(conn, ch, s)
+	c.collectPathTriggerMetrics(conn, ch, s)
+	c.timers.retain(units)
+	c.runs.retain(units)
+	c.paths.retain(units)
+
+	if c.watcher != nil {
+		c.collectStateTransitions(ch)
+	}
 	return nil
 }
 
-func (c *Collector) collectUnit(conn *dbus.Conn, ch chan<- prometheus.Metric, unit dbus.UnitStatus) error {
+// collectUnit collects the metrics of a single unit
+func (c *Collector) collectUnit(conn *dbus.Conn, ch chan<- prometheus.Metric, unit dbus.UnitStatus, s *scrape) error {
 
 	logger := c.logger.With("unit", unit.Name)
+	props := newUnitProperties(conn, unit.Name)
 
 	// Collect unit_state for all
-	err := c.collectUnitState(conn, ch, unit)
+	err := c.collectUnitState(ch, unit)
 	if err != nil {
 		logger.Warnf(errUnitMetricsMsg, err)
 		// TODO should we continue processing here?
 	}
 
+	err = c.collectUnitTransitionTimeMetrics(ch, unit, props)
+	if err != nil {
+		logger.Warnf(errUnitMetricsMsg, err)
+	}
+
+	err = s.addLastStart(unit.Name, props)
+	if err != nil {
+		logger.Warnf(errUnitMetricsMsg, err)
+	}
+
+	if c.chain != nil {
+		c.collectUnitCriticalChain(ch, unit)
+	}
+
 	switch {
 	case strings.HasSuffix(unit.Name, ".service"):
-		err = c.collectServiceMetainfo(conn, ch, unit)
+		err = c.collectServiceMetainfo(ch, unit, props)
 		if err != nil {
 			logger.Warnf(errUnitMetricsMsg, err)
 		}
 
-		err = c.collectServiceStartTimeMetrics(conn, ch, unit)
+		err = c.collectServiceStartTimeMetrics(ch, unit, props)
 		if err != nil {
 			logger.Warnf(errUnitMetricsMsg, err)
 		}
 
-		if *enableRestartsMetrics {
-			err = c.collectServiceRestartCount(conn, ch, unit)
+		// NRestarts wasn't added until systemd 235
+		if *enableRestartsMetrics || c.version.atLeast(235) {
+			err = c.collectServiceRestartCount(ch, unit, props)
 			if err != nil {
 				logger.Warnf(errUnitMetricsMsg, err)
 			}
 		}
 
-		err = c.collectServiceTasksMetrics(conn, ch, unit)
+		err = c.collectServiceResultMetrics(ch, unit, props, s)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+
+		err = c.collectServiceRunMetrics(ch, unit, props)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+
+		err = c.collectServiceTasksMetrics(ch, unit, props)
 		if err != nil {
 			logger.Warnf(errUnitMetricsMsg, err)
 		}
 
-		err = c.collectServiceProcessMetrics(conn, ch, unit)
+		cgSubpath, err := c.unitControlGroup("Service", unit, props)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		if *cgroupProcesses {
+			err = c.collectUnitProcessMetrics("Service", ch, unit, props, cgSubpath)
+		} else {
+			err = c.collectServiceProcessMetrics(ch, unit, props)
+		}
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitCPUUsageMetrics("Service", ch, unit, props, cgSubpath)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitCPUThrottleMetrics("Service", ch, unit, props, cgSubpath)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitMemoryMetrics("Service", ch, unit, props, cgSubpath)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitMemoryLimitMetrics("Service", ch, unit, props)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitIOMetrics("Service", ch, unit, props, cgSubpath)
 		if err != nil {
 			logger.Warnf(errUnitMetricsMsg, err)
 		}
-		err = c.collectUnitCPUUsageMetrics("Service", conn, ch, unit)
+		err = c.collectUnitPressureMetrics(ch, unit, cgSubpath)
 		if err != nil {
 			logger.Warnf(errUnitMetricsMsg, err)
 		}
 	case strings.HasSuffix(unit.Name, ".mount"):
-		err = c.collectMountMetainfo(conn, ch, unit)
+		err = c.collectMountMetainfo(ch, unit, props)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		cgSubpath, err := c.unitControlGroup("Mount", unit, props)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitCPUUsageMetrics("Mount", ch, unit, props, cgSubpath)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitCPUThrottleMetrics("Mount", ch, unit, props, cgSubpath)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitMemoryMetrics("Mount", ch, unit, props, cgSubpath)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitMemoryLimitMetrics("Mount", ch, unit, props)
 		if err != nil {
 			logger.Warnf(errUnitMetricsMsg, err)
 		}
-		err = c.collectUnitCPUUsageMetrics("Mount", conn, ch, unit)
+		err = c.collectUnitIOMetrics("Mount", ch, unit, props, cgSubpath)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitPressureMetrics(ch, unit, cgSubpath)
 		if err != nil {
 			logger.Warnf(errUnitMetricsMsg, err)
 		}
 	case strings.HasSuffix(unit.Name, ".timer"):
-		err := c.collectTimerTriggerTime(conn, ch, unit)
+		err := c.collectTimerTriggerTime(ch, unit, props)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectTimerScheduleMetrics(ch, unit, props)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = s.addTimer(unit.Name, props)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+	case strings.HasSuffix(unit.Name, ".target"):
+		err = c.collectTargetMetrics(ch, unit, props, s.activeStates)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+	case strings.HasSuffix(unit.Name, ".automount"):
+		err = c.collectAutomountMetrics(ch, unit, props, s.activeStates)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+	case strings.HasSuffix(unit.Name, ".path"):
+		err = c.collectPathMetrics(ch, unit, props, s)
 		if err != nil {
 			logger.Warnf(errUnitMetricsMsg, err)
 		}
 	case strings.HasSuffix(unit.Name, ".socket"):
-		err := c.collectSocketConnMetrics(conn, ch, unit)
+		err := c.collectSocketConnMetrics(ch, unit, props)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		cgSubpath, err := c.unitControlGroup("Socket", unit, props)
 		if err != nil {
 			logger.Warnf(errUnitMetricsMsg, err)
 		}
 		// Most sockets do not have a cpu cgroupfs entry, but a
 		// few do, notably docker.socket
-		err = c.collectUnitCPUUsageMetrics("Socket", conn, ch, unit)
+		err = c.collectUnitCPUUsageMetrics("Socket", ch, unit, props, cgSubpath)
 		if err != nil {
 			logger.Warnf(errUnitMetricsMsg, err)
 		}
 	case strings.HasSuffix(unit.Name, ".swap"):
-		err = c.collectUnitCPUUsageMetrics("Swap", conn, ch, unit)
+		cgSubpath, err := c.unitControlGroup("Swap", unit, props)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitCPUUsageMetrics("Swap", ch, unit, props, cgSubpath)
 		if err != nil {
 			logger.Warnf(errUnitMetricsMsg, err)
 		}
 	case strings.HasSuffix(unit.Name, ".slice"):
-		err = c.collectUnitCPUUsageMetrics("Slice", conn, ch, unit)
+		cgSubpath, err := c.unitControlGroup("Slice", unit, props)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitCPUUsageMetrics("Slice", ch, unit, props, cgSubpath)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitCPUThrottleMetrics("Slice", ch, unit, props, cgSubpath)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitMemoryMetrics("Slice", ch, unit, props, cgSubpath)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitMemoryLimitMetrics("Slice", ch, unit, props)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitIOMetrics("Slice", ch, unit, props, cgSubpath)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitPressureMetrics(ch, unit, cgSubpath)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		if *cgroupProcesses {
+			err = c.collectUnitProcessMetrics("Slice", ch, unit, props, cgSubpath)
+			if err != nil {
+				logger.Warnf(errUnitMetricsMsg, err)
+			}
+		}
+	case strings.HasSuffix(unit.Name, ".scope"):
+		cgSubpath, err := c.unitControlGroup("Scope", unit, props)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitCPUThrottleMetrics("Scope", ch, unit, props, cgSubpath)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitMemoryMetrics("Scope", ch, unit, props, cgSubpath)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitMemoryLimitMetrics("Scope", ch, unit, props)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitIOMetrics("Scope", ch, unit, props, cgSubpath)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+		err = c.collectUnitPressureMetrics(ch, unit, cgSubpath)
 		if err != nil {
 			logger.Warnf(errUnitMetricsMsg, err)
 		}
+		if *cgroupProcesses {
+			err = c.collectUnitProcessMetrics("Scope", ch, unit, props, cgSubpath)
+			if err != nil {
+				logger.Warnf(errUnitMetricsMsg, err)
+			}
+		}
 	default:
 		c.logger.Debugf(infoUnitNoHandler, unit.Name)
 	}
 
+	if *dbusAccounting {
+		err = c.collectUnitAccountingMetrics(ch, unit, props)
+		if err != nil {
+			logger.Warnf(errUnitMetricsMsg, err)
+		}
+	}
+
 	return nil
 }
 
-func (c *Collector) collectUnitState(conn *dbus.Conn, ch chan<- prometheus.Metric, unit dbus.UnitStatus) error {
-	//TODO: wrap GetUnitTypePropertyString(
-	// serviceTypeProperty, err := conn.GetUnitTypeProperty(unit.Name, "Timer", "NextElapseUSecMonotonic")
-
+func (c *Collector) collectUnitState(ch chan<- prometheus.Metric, unit dbus.UnitStatus) error {
 	for _, stateName := range unitStatesName {
 		isActive := 0.0
 		if stateName == unit.ActiveState {
@@ -358,51 +1201,92 @@ func (c *Collector) collectUnitState(conn *dbus.Conn, ch chan<- prometheus.Metri
 	return nil
 }
 
-// TODO metric is named unit but function is "Mount"
-func (c *Collector) collectMountMetainfo(conn *dbus.Conn, ch chan<- prometheus.Metric, unit dbus.UnitStatus) error {
-	//TODO: wrap GetUnitTypePropertyString(
-	serviceTypeProperty, err := conn.GetUnitTypeProperty(unit.Name, "Mount", "Type")
+// collectUnitTransitionTimeMetrics exports how long the unit's last start and
+// stop took, like systemd-analyze blame does for the boot. Monotonic
+// timestamps are used so that clock changes do not skew the durations. A
+// duration is only exported once the transition is complete, while a unit
+// is starting or stopping its timestamps belong to different transitions.
+func (c *Collector) collectUnitTransitionTimeMetrics(ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties) error {
+	inactiveExit, err := props.getUint64("Unit", "InactiveExitTimestampMonotonic")
 	if err != nil {
-		return errors.Wrapf(err, errGetPropertyMsg, "Type")
-	}
-
-	serviceType, ok := serviceTypeProperty.Value.Value().(string)
-	if !ok {
-		return errors.Errorf(errConvertStringPropertyMsg, "Type", serviceTypeProperty.Value.Value())
+		return err
 	}
-
-	ch <- prometheus.MustNewConstMetric(
-		c.unitInfo, prometheus.GaugeValue, 1.0,
-		unit.Name, parseUnitType(unit), serviceType, "")
-
-	return nil
-}
-
-// TODO the metric is named unit_info but function is named "Service"
-func (c *Collector) collectServiceMetainfo(conn *dbus.Conn, ch chan<- prometheus.Metric, unit dbus.UnitStatus) error {
-	serviceTypeProperty, err := conn.GetUnitTypeProperty(unit.Name, "Service", "Type")
+	activeEnter, err := props.getUint64("Unit", "ActiveEnterTimestampMonotonic")
 	if err != nil {
-		return errors.Wrapf(err, errGetPropertyMsg, "Type")
+		return err
 	}
-	serviceType, ok := serviceTypeProperty.Value.Value().(string)
-	if !ok {
-		return errors.Errorf(errConvertStringPropertyMsg, "Type", serviceTypeProperty.Value.Value())
+	activeExit, err := props.getUint64("Unit", "ActiveExitTimestampMonotonic")
+	if err != nil {
+		return err
+	}
+	inactiveEnter, err := props.getUint64("Unit", "InactiveEnterTimestampMonotonic")
+	if err != nil {
+		return err
+	}
+
+	if inactiveExit > 0 && activeEnter >= inactiveExit {
+		ch <- prometheus.MustNewConstMetric(
+			c.unitActivationTimeDesc, prometheus.GaugeValue,
+			float64(activeEnter-inactiveExit)/1e6, unit.Name, parseUnitType(unit))
+	}
+	if activeExit > 0 && inactiveEnter >= activeExit {
+		ch <- prometheus.MustNewConstMetric(
+			c.unitDeactivationTimeDesc, prometheus.GaugeValue,
+			float64(inactiveEnter-activeExit)/1e6, unit.Name, parseUnitType(unit))
+	}
+
+	return nil
+}
+
+func (c *Collector) collectUnitCriticalChain(ch chan<- prometheus.Metric, unit dbus.UnitStatus) {
+	offset, onChain, known := c.chain.lookup(unit.Name)
+	if !known {
+		return
+	}
+
+	onChainVal := 0.0
+	if onChain {
+		onChainVal = 1.0
+		ch <- prometheus.MustNewConstMetric(
+			c.unitCriticalChainOffsetDesc, prometheus.GaugeValue,
+			offset, unit.Name, parseUnitType(unit))
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.unitCriticalChainDesc, prometheus.GaugeValue,
+		onChainVal, unit.Name, parseUnitType(unit))
+}
+
+// TODO metric is named unit but function is "Mount"
+func (c *Collector) collectMountMetainfo(ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties) error {
+	serviceType, err := props.getString("Mount", "Type")
+	if err != nil {
+		return err
 	}
 
 	ch <- prometheus.MustNewConstMetric(
 		c.unitInfo, prometheus.GaugeValue, 1.0,
-		unit.Name, parseUnitType(unit), "", serviceType)
+		unit.Name, parseUnitType(unit), serviceType, "")
+
 	return nil
 }
 
-func (c *Collector) collectServiceRestartCount(conn *dbus.Conn, ch chan<- prometheus.Metric, unit dbus.UnitStatus) error {
-	restartsCount, err := conn.GetUnitTypeProperty(unit.Name, "Service", "NRestarts")
+// TODO the metric is named unit_info but function is named "Service"
+func (c *Collector) collectServiceMetainfo(ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties) error {
+	serviceType, err := props.getString("Service", "Type")
 	if err != nil {
-		return errors.Wrapf(err, errGetPropertyMsg, "NRestarts")
+		return err
 	}
-	val, ok := restartsCount.Value.Value().(uint32)
-	if !ok {
-		return errors.Errorf(errConvertUint32PropertyMsg, "NRestarts", restartsCount.Value.Value())
+
+	ch <- prometheus.MustNewConstMetric(
+		c.unitInfo, prometheus.GaugeValue, 1.0,
+		unit.Name, parseUnitType(unit), "", serviceType)
+	return nil
+}
+
+func (c *Collector) collectServiceRestartCount(ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties) error {
+	val, err := props.getUint32("Service", "NRestarts")
+	if err != nil {
+		return err
 	}
 	ch <- prometheus.MustNewConstMetric(
 		c.nRestartsDesc, prometheus.CounterValue,
@@ -410,19 +1294,137 @@ func (c *Collector) collectServiceRestartCount(conn *dbus.Conn, ch chan<- promet
 	return nil
 }
 
+// collectServiceResultMetrics exports how the service last finished, its main
+// process's exit status and when it failed, if it is in the failed state
+func (c *Collector) collectServiceResultMetrics(ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties, s *scrape) error {
+	result, err := readServiceResult(props)
+	if err != nil {
+		return err
+	}
+	s.addServiceResult(unit.Name, result)
+
+	collectStateSet(ch, c.serviceResultDesc, serviceResults, result.result, unit.Name)
+	ch <- prometheus.MustNewConstMetric(
+		c.serviceExecMainStatusDesc, prometheus.GaugeValue,
+		float64(result.status), unit.Name)
+
+	code, err := props.getInt32("Service", "ExecMainCode")
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.serviceExecMainCodeDesc, prometheus.GaugeValue,
+		float64(code), unit.Name)
+
+	if unit.ActiveState == "failed" {
+		failedTime, err := props.getUint64("Unit", "InactiveEnterTimestamp")
+		if err != nil {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.serviceFailedTimeDesc, prometheus.GaugeValue,
+			float64(failedTime)/1e6, unit.Name)
+	}
+
+	return nil
+}
+
+// collectServiceRunMetrics exports the history of runs of oneshot services
+// and services triggered by timers
+func (c *Collector) collectServiceRunMetrics(ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties) error {
+	serviceType, err := props.getString("Service", "Type")
+	if err != nil {
+		return err
+	}
+	batch := serviceType == "oneshot"
+	if !batch {
+		// TriggeredBy wasn't added until systemd 240
+		if triggeredBy, err := props.get("Unit", "TriggeredBy"); err == nil {
+			names, _ := triggeredBy.([]string)
+			for _, name := range names {
+				if strings.HasSuffix(name, ".timer") {
+					batch = true
+				}
+			}
+		}
+	}
+	if !batch {
+		return nil
+	}
+
+	// InvocationID wasn't added until systemd 232
+	invocationID, err := props.get("Unit", "InvocationID")
+	if err != nil {
+		return nil
+	}
+	id, _ := invocationID.([]byte)
+	result, err := props.getString("Service", "Result")
+	if err != nil {
+		return err
+	}
+	start, err := props.getUint64("Service", "ExecMainStartTimestamp")
+	if err != nil {
+		return err
+	}
+	exit, err := props.getUint64("Service", "ExecMainExitTimestamp")
+	if err != nil {
+		return err
+	}
+
+	runs, ok := c.runs.observe(unit.Name, serviceRun{
+		invocationID: hex.EncodeToString(id),
+		result:       result,
+		start:        start,
+		exit:         exit,
+	})
+	if !ok {
+		return nil
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.serviceRunDurationDesc, prometheus.GaugeValue,
+		runs.duration, unit.Name)
+	collectStateSet(ch, c.serviceRunResultDesc, serviceResults, runs.result, unit.Name)
+	if runs.lastSuccess > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.serviceLastSuccessDesc, prometheus.GaugeValue,
+			runs.lastSuccess, unit.Name)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.serviceRunSuccessesDesc, prometheus.CounterValue,
+		float64(runs.successes), unit.Name)
+	ch <- prometheus.MustNewConstMetric(
+		c.serviceRunFailuresDesc, prometheus.CounterValue,
+		float64(runs.failures), unit.Name)
+
+	return nil
+}
+
+// readServiceResult reads how the last run of the service went
+func readServiceResult(props *unitProperties) (serviceResult, error) {
+	var result serviceResult
+	var err error
+	result.result, err = props.getString("Service", "Result")
+	if err != nil {
+		return result, err
+	}
+	result.status, err = props.getInt32("Service", "ExecMainStatus")
+	if err != nil {
+		return result, err
+	}
+	result.exitTime, err = props.getUint64("Service", "ExecMainExitTimestamp")
+	return result, err
+}
+
 // TODO metric is named unit but function is "Service"
-func (c *Collector) collectServiceStartTimeMetrics(conn *dbus.Conn, ch chan<- prometheus.Metric, unit dbus.UnitStatus) error {
+func (c *Collector) collectServiceStartTimeMetrics(ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties) error {
 	var startTimeUsec uint64
 
 	switch unit.ActiveState {
 	case "active":
-		timestampValue, err := conn.GetUnitProperty(unit.Name, "ActiveEnterTimestamp")
+		startTime, err := props.getUint64("Unit", "ActiveEnterTimestamp")
 		if err != nil {
-			return errors.Wrapf(err, errGetPropertyMsg, "ActiveEnterTimestamp")
-		}
-		startTime, ok := timestampValue.Value.Value().(uint64)
-		if !ok {
-			return errors.Errorf(errConvertUint64PropertyMsg, "ActiveEnterTimestamp", timestampValue.Value.Value())
+			return err
 		}
 		startTimeUsec = startTime
 
@@ -437,18 +1439,13 @@ func (c *Collector) collectServiceStartTimeMetrics(conn *dbus.Conn, ch chan<- pr
 	return nil
 }
 
-func (c *Collector) collectServiceProcessMetrics(conn *dbus.Conn, ch chan<- prometheus.Metric, unit dbus.UnitStatus) error {
+func (c *Collector) collectServiceProcessMetrics(ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties) error {
 	// TODO: ExecStart type property, has a slice with process information.
 	// When systemd manages multiple processes, maybe we should add them all?
 
-	mainPID, err := conn.GetUnitTypeProperty(unit.Name, "Service", "MainPID")
+	pid, err := props.getUint32("Service", "MainPID")
 	if err != nil {
-		return errors.Wrapf(err, errGetPropertyMsg, "MainPID")
-	}
-
-	pid, ok := mainPID.Value.Value().(uint32)
-	if !ok {
-		return errors.Errorf(errConvertUint32PropertyMsg, "MainPID", mainPID.Value.Value())
+		return err
 	}
 
 	// MainPID 0 when the service currently has no main PID
@@ -499,32 +1496,107 @@ func (c *Collector) collectServiceProcessMetrics(conn *dbus.Conn, ch chan<- prom
 	return nil
 }
 
-func (c *Collector) mustGetUnitStringTypeProperty(unitType string,
-	propName string, defaultVal string, conn *dbus.Conn, unit dbus.UnitStatus) string {
+// collectUnitProcessMetrics sums up the process metrics of every process in
+// the unit's control group. Resource limits apply to each process on its own
+// and cannot be summed, so they are only reported for the main process of
+// services.
+func (c *Collector) collectUnitProcessMetrics(unitType string, ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties, cgSubpath string) error {
+	if cgSubpath == "" {
+		return nil
+	}
 
-	prop, err := conn.GetUnitTypeProperty(unit.Name, unitType, propName)
+	pids, err := cgroupPIDs(cgSubpath)
 	if err != nil {
-		c.logger.Debugf(errGetPropertyMsg, propName)
-		return defaultVal
+		return errors.Wrapf(err, errControlGroupReadMsg, "processes")
 	}
-	propVal, ok := prop.Value.Value().(string)
-	if !ok {
-		c.logger.Debugf(errConvertStringPropertyMsg, propName, prop.Value.Value())
+
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return err
+	}
+
+	var cpuTime float64
+	var vsize uint
+	var rss, fds, processes int
+	for _, pid := range pids {
+		// Processes may exit between listing and reading them
+		p, err := fs.NewProc(pid)
+		if err != nil {
+			continue
+		}
+		stat, err := p.NewStat()
+		if err != nil {
+			continue
+		}
+		if *enableFDMetrics {
+			// The file descriptors of processes running as another user
+			// are not readable, which should not hide their other metrics
+			if n, err := p.FileDescriptorsLen(); err == nil {
+				fds += n
+			}
+		}
+		cpuTime += stat.CPUTime()
+		vsize += stat.VirtualMemory()
+		rss += stat.ResidentMemory()
+		processes++
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.unitProcessesDesc, prometheus.GaugeValue,
+		float64(processes), unit.Name, parseUnitType(unit))
+	ch <- prometheus.MustNewConstMetric(
+		c.cpuTotalDesc, prometheus.CounterValue,
+		cpuTime, unit.Name)
+	ch <- prometheus.MustNewConstMetric(c.vsize, prometheus.GaugeValue,
+		float64(vsize), unit.Name)
+	ch <- prometheus.MustNewConstMetric(c.rss, prometheus.GaugeValue,
+		float64(rss), unit.Name)
+	if *enableFDMetrics {
+		ch <- prometheus.MustNewConstMetric(c.openFDs, prometheus.GaugeValue,
+			float64(fds), unit.Name)
+	}
+
+	if unitType != "Service" {
+		return nil
+	}
+	pid, err := props.getUint32("Service", "MainPID")
+	if err != nil || pid == 0 {
+		return err
+	}
+	p, err := fs.NewProc(int(pid))
+	if err != nil {
+		return err
+	}
+	limits, err := p.NewLimits()
+	if err != nil {
+		return errors.Wrap(err, "couldn't get process limits")
+	}
+	ch <- prometheus.MustNewConstMetric(c.maxFDs, prometheus.GaugeValue,
+		float64(limits.OpenFiles), unit.Name)
+	ch <- prometheus.MustNewConstMetric(c.maxVsize, prometheus.GaugeValue,
+		float64(limits.AddressSpace), unit.Name)
+
+	return nil
+}
+
+func (c *Collector) mustGetUnitStringTypeProperty(unitType string,
+	propName string, defaultVal string, props *unitProperties) string {
+
+	propVal, err := props.getString(unitType, propName)
+	if err != nil {
+		c.logger.Debug(err)
 		return defaultVal
 	}
 	return propVal
 }
 
 // A number of unit types support the 'ControlGroup' property needed to allow us to directly read their
-// resource usage from the kernel's cgroupfs cpu hierarchy. The only change is which dbus item we are querying
-func (c *Collector) collectUnitCPUUsageMetrics(unitType string, conn *dbus.Conn, ch chan<- prometheus.Metric, unit dbus.UnitStatus) error {
-	propCGSubpath, err := conn.GetUnitTypeProperty(unit.Name, unitType, "ControlGroup")
+// resource usage from the kernel's cgroupfs hierarchies. The only change is which dbus item we are querying.
+// An empty subpath without error means there is currently nothing to record for the unit
+func (c *Collector) unitControlGroup(unitType string, unit dbus.UnitStatus, props *unitProperties) (string, error) {
+	cgSubpath, err := props.getString(unitType, "ControlGroup")
 	if err != nil {
-		return errors.Wrapf(err, errGetPropertyMsg, "ControlGroup")
-	}
-	cgSubpath, ok := propCGSubpath.Value.Value().(string)
-	if !ok {
-		return errors.Errorf(errConvertStringPropertyMsg, "ControlGroup", propCGSubpath.Value.Value())
+		return "", err
 	}
 
 	switch {
@@ -532,28 +1604,32 @@ func (c *Collector) collectUnitCPUUsageMetrics(unitType string, conn *dbus.Conn,
 		cgSubpath == "" && unit.ActiveState == "failed":
 		// Expected condition, systemd has cleaned up and
 		// we have nothing to record
-		return nil
+		return "", nil
 	case cgSubpath == "" && unit.ActiveState == "active":
 		// Unexpected. Why is there no cgroup on an active unit?
-		subType := c.mustGetUnitStringTypeProperty(unitType, "Type", "unknown", conn, unit)
-		slice := c.mustGetUnitStringTypeProperty(unitType, "Slice", "unknown", conn, unit)
-		return errors.Errorf("got 'no cgroup' from systemd for active unit (state=%s subtype=%s slice=%s)", unit.ActiveState, subType, slice)
+		subType := c.mustGetUnitStringTypeProperty(unitType, "Type", "unknown", props)
+		slice := c.mustGetUnitStringTypeProperty(unitType, "Slice", "unknown", props)
+		return "", errors.Errorf("got 'no cgroup' from systemd for active unit (state=%s subtype=%s slice=%s)", unit.ActiveState, subType, slice)
 	case cgSubpath == "":
 		// We are likely reading a unit that is currently changing state, so
 		// we record this and bail
-		subType := c.mustGetUnitStringTypeProperty(unitType, "Type", "unknown", conn, unit)
-		slice := c.mustGetUnitStringTypeProperty(unitType, "Slice", "unknown", conn, unit)
+		subType := c.mustGetUnitStringTypeProperty(unitType, "Type", "unknown", props)
+		slice := c.mustGetUnitStringTypeProperty(unitType, "Slice", "unknown", props)
 		log.Debugf("Read 'no cgroup' from unit (name=%s state=%s subtype=%s slice=%s) ", unit.Name, unit.ActiveState, subType, slice)
+		return "", nil
+	}
+
+	return cgSubpath, nil
+}
+
+func (c *Collector) collectUnitCPUUsageMetrics(unitType string, ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties, cgSubpath string) error {
+	if cgSubpath == "" {
 		return nil
 	}
 
-	propCPUAcct, err := conn.GetUnitTypeProperty(unit.Name, unitType, "CPUAccounting")
+	cpuAcct, err := props.getBool(unitType, "CPUAccounting")
 	if err != nil {
-		return errors.Wrapf(err, errGetPropertyMsg, "CPUAccounting")
-	}
-	cpuAcct, ok := propCPUAcct.Value.Value().(bool)
-	if !ok {
-		return errors.Errorf(errConvertStringPropertyMsg, "CPUAccounting", propCPUAcct.Value.Value())
+		return err
 	}
 	if !cpuAcct {
 		return nil
@@ -578,50 +1654,321 @@ func (c *Collector) collectUnitCPUUsageMetrics(unitType string, conn *dbus.Conn,
 		c.unitCPUTotal, prometheus.CounterValue,
 		sysSeconds, unit.Name, parseUnitType(unit), "system")
 
+	if c.perCPUUnitsPattern == nil || !c.perCPUUnitsPattern.MatchString(unit.Name) {
+		return nil
+	}
+	// The unified hierarchy only reports usage summed across all cores
+	if !cpuUsage.PerCPU {
+		log.Debugf("per-CPU usage is not available for unit (unit=%s)", unit.Name)
+		return nil
+	}
+	for _, cpu := range cpuUsage.CPUs {
+		cpuID := strconv.FormatUint(uint64(cpu.CPUId), 10)
+		ch <- prometheus.MustNewConstMetric(
+			c.unitCPUCoreTotal, prometheus.CounterValue,
+			float64(cpu.UserNanosec)/1000000000.0, unit.Name, parseUnitType(unit), "user", cpuID)
+		ch <- prometheus.MustNewConstMetric(
+			c.unitCPUCoreTotal, prometheus.CounterValue,
+			float64(cpu.SystemNanosec)/1000000000.0, unit.Name, parseUnitType(unit), "system", cpuID)
+	}
+
+	return nil
+}
+
+func (c *Collector) collectUnitCPUThrottleMetrics(unitType string, ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties, cgSubpath string) error {
+	// systemd reports an unset quota or period as MaxUint64. Older
+	// versions lack CPUQuotaPeriodUSec, skip it there
+	if quota, err := props.getUint64(unitType, "CPUQuotaPerSecUSec"); err == nil && quota != math.MaxUint64 {
+		ch <- prometheus.MustNewConstMetric(
+			c.unitCPUQuotaDesc, prometheus.GaugeValue,
+			float64(quota)/1000000.0, unit.Name, parseUnitType(unit))
+	}
+	if period, err := props.getUint64(unitType, "CPUQuotaPeriodUSec"); err == nil && period != math.MaxUint64 {
+		ch <- prometheus.MustNewConstMetric(
+			c.unitCPUQuotaPeriodDesc, prometheus.GaugeValue,
+			float64(period)/1000000.0, unit.Name, parseUnitType(unit))
+	}
+
+	if cgSubpath == "" {
+		return nil
+	}
+
+	throttleStat, err := NewCPUThrottleStat(cgSubpath)
+	if err != nil {
+		return errors.Wrapf(err, errControlGroupReadMsg, "CPU throttling")
+	}
+	if throttleStat == nil {
+		return nil
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.unitCPUPeriodsDesc, prometheus.CounterValue,
+		float64(throttleStat.Periods), unit.Name, parseUnitType(unit))
+	ch <- prometheus.MustNewConstMetric(
+		c.unitCPUThrottledPeriodsDesc, prometheus.CounterValue,
+		float64(throttleStat.ThrottledPeriods), unit.Name, parseUnitType(unit))
+	ch <- prometheus.MustNewConstMetric(
+		c.unitCPUThrottledDesc, prometheus.CounterValue,
+		float64(throttleStat.ThrottledNanosecs)/1000000000.0, unit.Name, parseUnitType(unit))
+
+	return nil
+}
+
+func (c *Collector) collectUnitMemoryMetrics(unitType string, ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties, cgSubpath string) error {
+	if cgSubpath == "" {
+		return nil
+	}
+
+	memAcct, err := props.getBool(unitType, "MemoryAccounting")
+	if err != nil {
+		return err
+	}
+	if !memAcct {
+		return nil
+	}
+
+	memStat, err := NewMemStat(cgSubpath)
+	if err != nil {
+		return errors.Wrapf(err, errControlGroupReadMsg, "memory usage")
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.unitMemoryUsageDesc, prometheus.GaugeValue,
+		float64(memStat.UsageBytes), unit.Name, parseUnitType(unit))
+	if memStat.HasPeak {
+		ch <- prometheus.MustNewConstMetric(
+			c.unitMemoryPeakDesc, prometheus.GaugeValue,
+			float64(memStat.PeakBytes), unit.Name, parseUnitType(unit))
+	}
+	if memStat.HasSwap {
+		ch <- prometheus.MustNewConstMetric(
+			c.unitMemorySwapUsageDesc, prometheus.GaugeValue,
+			float64(memStat.SwapBytes), unit.Name, parseUnitType(unit))
+	}
+	for kind, val := range memStat.Breakdown {
+		ch <- prometheus.MustNewConstMetric(
+			c.unitMemoryStatDesc, prometheus.GaugeValue,
+			float64(val), unit.Name, parseUnitType(unit), kind)
+	}
+	for event, val := range memStat.Events {
+		ch <- prometheus.MustNewConstMetric(
+			c.unitMemoryEventsDesc, prometheus.CounterValue,
+			float64(val), unit.Name, parseUnitType(unit), event)
+	}
+
+	return nil
+}
+
+// memoryLimitProperties maps the unit properties holding memory limits to
+// the limit label they are exported with
+var memoryLimitProperties = map[string]string{
+	"MemoryMin":     "min",
+	"MemoryLow":     "low",
+	"MemoryHigh":    "high",
+	"MemoryMax":     "max",
+	"MemorySwapMax": "swap_max",
+}
+
+func (c *Collector) collectUnitMemoryLimitMetrics(unitType string, ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties) error {
+	for propName, limit := range memoryLimitProperties {
+		// Older systemd versions lack some of the properties, skip those
+		val, err := props.getUint64(unitType, propName)
+		if err != nil {
+			continue
+		}
+		// systemd reports infinity as MaxUint64
+		if val == math.MaxUint64 {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.unitMemoryLimitDesc, prometheus.GaugeValue,
+			float64(val), unit.Name, parseUnitType(unit), limit)
+	}
+
+	return nil
+}
+
+func (c *Collector) collectUnitIOMetrics(unitType string, ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties, cgSubpath string) error {
+	if cgSubpath == "" {
+		return nil
+	}
+
+	// systemd before 230 has no IOAccounting, and on the legacy hierarchy
+	// newer versions may still account through the deprecated
+	// BlockIOAccounting setting
+	ioAcct, ioErr := props.getBool(unitType, "IOAccounting")
+	if !ioAcct {
+		blockIOAcct, err := props.getBool(unitType, "BlockIOAccounting")
+		if err != nil && ioErr != nil {
+			return err
+		}
+		ioAcct = blockIOAcct
+	}
+	if !ioAcct {
+		return nil
+	}
+
+	ioStat, err := NewIOStat(cgSubpath)
+	if err != nil {
+		return errors.Wrapf(err, errControlGroupReadMsg, "block IO")
+	}
+
+	for _, dev := range ioStat.Devices {
+		device := fmt.Sprintf("%d:%d", dev.Major, dev.Minor)
+		if *resolveBlockDevices {
+			if name := blockDeviceName(dev.Major, dev.Minor); name != "" {
+				device = name
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.unitIOReadBytesDesc, prometheus.CounterValue,
+			float64(dev.ReadBytes), unit.Name, parseUnitType(unit), device)
+		ch <- prometheus.MustNewConstMetric(
+			c.unitIOWriteBytesDesc, prometheus.CounterValue,
+			float64(dev.WriteBytes), unit.Name, parseUnitType(unit), device)
+		ch <- prometheus.MustNewConstMetric(
+			c.unitIOReadsDesc, prometheus.CounterValue,
+			float64(dev.ReadOps), unit.Name, parseUnitType(unit), device)
+		ch <- prometheus.MustNewConstMetric(
+			c.unitIOWritesDesc, prometheus.CounterValue,
+			float64(dev.WriteOps), unit.Name, parseUnitType(unit), device)
+	}
+
+	return nil
+}
+
+// pressureResources are the resources the kernel keeps pressure stall
+// information about
+var pressureResources = []string{"cpu", "memory", "io"}
+
+func (c *Collector) collectUnitPressureMetrics(ch chan<- prometheus.Metric, unit dbus.UnitStatus, cgSubpath string) error {
+	if cgSubpath == "" {
+		return nil
+	}
+
+	for _, resource := range pressureResources {
+		stat, err := NewPressureStat(cgSubpath, resource)
+		if err != nil {
+			return errors.Wrapf(err, errControlGroupReadMsg, resource+" pressure")
+		}
+		if stat == nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.unitPressureWaitingDesc, prometheus.CounterValue,
+			float64(stat.SomeMicrosecs)/1e6, unit.Name, parseUnitType(unit), resource)
+		if stat.HasFull {
+			ch <- prometheus.MustNewConstMetric(
+				c.unitPressureStalledDesc, prometheus.CounterValue,
+				float64(stat.FullMicrosecs)/1e6, unit.Name, parseUnitType(unit), resource)
+		}
+	}
+
+	return nil
+}
+
+// accountingInterfaces maps the unit types systemd does resource accounting
+// for to the dbus interface their accounting properties live on
+var accountingInterfaces = map[string]string{
+	"service": "Service",
+	"slice":   "Slice",
+	"scope":   "Scope",
+	"socket":  "Socket",
+	"mount":   "Mount",
+	"swap":    "Swap",
+}
+
+// collectUnitAccountingMetrics exports the resource usage systemd itself
+// keeps track of. Depending on the systemd version and the *Accounting=
+// settings of the unit not all of them are available, systemd reports those
+// as MaxUint64 and older versions lack some of the properties entirely.
+func (c *Collector) collectUnitAccountingMetrics(ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties) error {
+	unitType := parseUnitType(unit)
+	iface, ok := accountingInterfaces[unitType]
+	if !ok {
+		return nil
+	}
+
+	accountingProperties := []struct {
+		name      string
+		desc      *prometheus.Desc
+		valueType prometheus.ValueType
+		scale     float64
+	}{
+		{"MemoryCurrent", c.accountingMemoryDesc, prometheus.GaugeValue, 1},
+		{"CPUUsageNSec", c.accountingCPUDesc, prometheus.CounterValue, 1000000000.0},
+		{"TasksCurrent", c.accountingTasksDesc, prometheus.GaugeValue, 1},
+		{"IOReadBytes", c.accountingIOReadBytesDesc, prometheus.CounterValue, 1},
+		{"IOWriteBytes", c.accountingIOWriteBytesDesc, prometheus.CounterValue, 1},
+		{"IOReadOperations", c.accountingIOReadsDesc, prometheus.CounterValue, 1},
+		{"IOWriteOperations", c.accountingIOWritesDesc, prometheus.CounterValue, 1},
+		{"IPIngressBytes", c.accountingIPIngressBytesDesc, prometheus.CounterValue, 1},
+		{"IPEgressBytes", c.accountingIPEgressBytesDesc, prometheus.CounterValue, 1},
+		{"IPIngressPackets", c.accountingIPIngressPktsDesc, prometheus.CounterValue, 1},
+		{"IPEgressPackets", c.accountingIPEgressPktsDesc, prometheus.CounterValue, 1},
+	}
+
+	for _, prop := range accountingProperties {
+		// TasksCurrent of services is already exported as
+		// systemd_unit_tasks_current
+		if prop.name == "TasksCurrent" && iface == "Service" {
+			continue
+		}
+		val, err := props.getUint64(iface, prop.name)
+		if err != nil {
+			continue
+		}
+		if val == math.MaxUint64 {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			prop.desc, prop.valueType,
+			float64(val)/prop.scale, unit.Name, unitType)
+	}
+
 	return nil
 }
 
-func (c *Collector) collectSocketConnMetrics(conn *dbus.Conn, ch chan<- prometheus.Metric, unit dbus.UnitStatus) error {
-	acceptedConnectionCount, err := conn.GetUnitTypeProperty(unit.Name, "Socket", "NAccepted")
+func (c *Collector) collectSocketConnMetrics(ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties) error {
+	acceptedConnectionCount, err := props.getUint32("Socket", "NAccepted")
 	if err != nil {
-		return errors.Wrapf(err, errGetPropertyMsg, "NAccepted")
+		return err
 	}
 
 	ch <- prometheus.MustNewConstMetric(
 		c.socketAcceptedConnectionsDesc, prometheus.CounterValue,
-		float64(acceptedConnectionCount.Value.Value().(uint32)), unit.Name)
+		float64(acceptedConnectionCount), unit.Name)
 
-	currentConnectionCount, err := conn.GetUnitTypeProperty(unit.Name, "Socket", "NConnections")
+	currentConnectionCount, err := props.getUint32("Socket", "NConnections")
 	if err != nil {
-		return errors.Wrapf(err, errGetPropertyMsg, "NConnections")
+		return err
 	}
 	ch <- prometheus.MustNewConstMetric(
 		c.socketCurrentConnectionsDesc, prometheus.GaugeValue,
-		float64(currentConnectionCount.Value.Value().(uint32)), unit.Name)
+		float64(currentConnectionCount), unit.Name)
 
-	// NRefused wasn't added until systemd 239.
-	refusedConnectionCount, err := conn.GetUnitTypeProperty(unit.Name, "Socket", "NRefused")
+	// NRefused wasn't added until systemd 239. If the version is unknown
+	// we try anyway.
+	if c.version.known() && !c.version.atLeast(239) {
+		return nil
+	}
+	refusedConnectionCount, err := props.getUint32("Socket", "NRefused")
 	if err != nil {
-		return errors.Wrapf(err, errGetPropertyMsg, "NRefused")
+		return err
 	}
 	ch <- prometheus.MustNewConstMetric(
 		c.socketRefusedConnectionsDesc, prometheus.GaugeValue,
-		float64(refusedConnectionCount.Value.Value().(uint32)), unit.Name)
+		float64(refusedConnectionCount), unit.Name)
 
 	return nil
 }
 
 // TODO either the unit should be called service_tasks, or it should work for all
 // units. It's currently named unit_task
-func (c *Collector) collectServiceTasksMetrics(conn *dbus.Conn, ch chan<- prometheus.Metric, unit dbus.UnitStatus) error {
-	tasksCurrentCount, err := conn.GetUnitTypeProperty(unit.Name, "Service", "TasksCurrent")
+func (c *Collector) collectServiceTasksMetrics(ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties) error {
+	currentCount, err := props.getUint64("Service", "TasksCurrent")
 	if err != nil {
-		return errors.Wrapf(err, errGetPropertyMsg, "TasksCurrent")
-	}
-
-	currentCount, ok := tasksCurrentCount.Value.Value().(uint64)
-	if !ok {
-		return errors.Errorf(errConvertUint64PropertyMsg, "TasksCurrent", tasksCurrentCount.Value.Value())
+		return err
 	}
 
 	// Don't set if tasksCurrent if dbus reports MaxUint64.
@@ -631,14 +1978,9 @@ func (c *Collector) collectServiceTasksMetrics(conn *dbus.Conn, ch chan<- promet
 			float64(currentCount), unit.Name)
 	}
 
-	tasksMaxCount, err := conn.GetUnitTypeProperty(unit.Name, "Service", "TasksMax")
+	maxCount, err := props.getUint64("Service", "TasksMax")
 	if err != nil {
-		return errors.Wrapf(err, errGetPropertyMsg, "TasksMax")
-	}
-
-	maxCount, ok := tasksMaxCount.Value.Value().(uint64)
-	if !ok {
-		return errors.Errorf(errConvertUint64PropertyMsg, "TasksMax", tasksMaxCount.Value.Value())
+		return err
 	}
 	// Don't set if tasksMax if dbus reports MaxUint64.
 	if maxCount != math.MaxUint64 {
@@ -650,14 +1992,10 @@ func (c *Collector) collectServiceTasksMetrics(conn *dbus.Conn, ch chan<- promet
 	return nil
 }
 
-func (c *Collector) collectTimerTriggerTime(conn *dbus.Conn, ch chan<- prometheus.Metric, unit dbus.UnitStatus) error {
-	lastTriggerValue, err := conn.GetUnitTypeProperty(unit.Name, "Timer", "LastTriggerUSec")
+func (c *Collector) collectTimerTriggerTime(ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties) error {
+	val, err := props.getUint64("Timer", "LastTriggerUSec")
 	if err != nil {
-		return errors.Wrapf(err, errGetPropertyMsg, "LastTriggerUSec")
-	}
-	val, ok := lastTriggerValue.Value.Value().(uint64)
-	if !ok {
-		return errors.Errorf(errConvertUint64PropertyMsg, "LastTriggerUSec", lastTriggerValue.Value.Value())
+		return err
 	}
 	ch <- prometheus.MustNewConstMetric(
 		c.timerLastTriggerDesc, prometheus.GaugeValue,
@@ -665,11 +2003,421 @@ func (c *Collector) collectTimerTriggerTime(conn *dbus.Conn, ch chan<- prometheu
 	return nil
 }
 
-func (c *Collector) newDbus() (*dbus.Conn, error) {
-	if *systemdPrivate {
-		return dbus.NewSystemdConnection()
+func (c *Collector) collectTimerScheduleMetrics(ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties) error {
+	realtime, err := props.getUint64("Timer", "NextElapseUSecRealtime")
+	if err != nil {
+		return err
+	}
+	monotonic, err := props.getUint64("Timer", "NextElapseUSecMonotonic")
+	if err != nil {
+		return err
+	}
+	next := timerNextElapse(realtime, monotonic)
+	if next > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.timerNextTriggerDesc, prometheus.GaugeValue,
+			float64(next)/1e6, unit.Name)
+	}
+
+	lastTrigger, err := props.getUint64("Timer", "LastTriggerUSec")
+	if err != nil {
+		return err
+	}
+	if delay, ok := c.timers.observe(unit.Name, lastTrigger, next); ok {
+		ch <- prometheus.MustNewConstMetric(
+			c.timerLastTriggerDelayDesc, prometheus.GaugeValue,
+			delay, unit.Name)
+	}
+
+	calendar, err := props.get("Timer", "TimersCalendar")
+	if err != nil {
+		return err
+	}
+	monotonicTimers, err := props.get("Timer", "TimersMonotonic")
+	if err != nil {
+		return err
+	}
+	persistent, err := props.getBool("Timer", "Persistent")
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.timerInfoDesc, prometheus.GaugeValue, 1.0,
+		unit.Name, formatTimersCalendar(calendar), formatTimersMonotonic(monotonicTimers),
+		strconv.FormatBool(persistent))
+
+	accuracy, err := props.getUint64("Timer", "AccuracyUSec")
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.timerAccuracyDesc, prometheus.GaugeValue,
+		float64(accuracy)/1e6, unit.Name)
+
+	// RandomizedDelayUSec wasn't added until systemd 229
+	randomizedDelay, err := props.getUint64("Timer", "RandomizedDelayUSec")
+	if err != nil {
+		return nil
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.timerRandomizedDelayDesc, prometheus.GaugeValue,
+		float64(randomizedDelay)/1e6, unit.Name)
+
+	return nil
+}
+
+// collectTimerResultMetrics exports how the last run of the service each timer
+// triggers went, labeled with the timer's name. It runs once all units were
+// collected, so that the results of services collected in the same scrape
+// can be reused.
+func (c *Collector) collectTimerResultMetrics(conn *dbus.Conn, ch chan<- prometheus.Metric, s *scrape) {
+	for timer, service := range s.timerServices {
+		result, ok := s.serviceResults[service]
+		if !ok {
+			var err error
+			result, err = readServiceResult(newUnitProperties(conn, service))
+			if err != nil {
+				c.logger.With("unit", timer).Warnf(errUnitMetricsMsg, err)
+				continue
+			}
+		}
+
+		collectStateSet(ch, c.timerLastResultDesc, serviceResults, result.result, timer, service)
+		ch <- prometheus.MustNewConstMetric(
+			c.timerLastExitStatusDesc, prometheus.GaugeValue,
+			float64(result.status), timer, service)
+		if result.exitTime > 0 {
+			ch <- prometheus.MustNewConstMetric(
+				c.timerLastExitTimeDesc, prometheus.GaugeValue,
+				float64(result.exitTime)/1e6, timer, service)
+		}
+	}
+}
+
+// stateSet returns the states a property is exported as a state set of,
+// the known states plus state itself should a newer systemd report one we
+// do not know about
+func stateSet(known []string, state string) []string {
+	for _, s := range known {
+		if s == state {
+			return known
+		}
+	}
+	return append(append([]string{}, known...), state)
+}
+
+// collectStateSet exports state as a state set of the known states, with the
+// state as the last label following labelValues
+func collectStateSet(ch chan<- prometheus.Metric, desc *prometheus.Desc, known []string, state string, labelValues ...string) {
+	for _, s := range stateSet(known, state) {
+		isState := 0.0
+		if s == state {
+			isState = 1.0
+		}
+		values := append(append([]string{}, labelValues...), s)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, isState, values...)
+	}
+}
+
+func (c *Collector) collectPathMetrics(ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties, s *scrape) error {
+	triggered, err := props.getString("Path", "Unit")
+	if err != nil {
+		return err
+	}
+	paths, err := props.get("Path", "Paths")
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.pathInfoDesc, prometheus.GaugeValue, 1.0,
+		unit.Name, triggered, formatPaths(paths))
+
+	result, err := props.getString("Path", "Result")
+	if err != nil {
+		return err
+	}
+	collectStateSet(ch, c.pathResultDesc, pathResults, result, unit.Name)
+
+	s.addPath(unit.Name, triggered)
+	return nil
+}
+
+// collectPathTriggerMetrics exports when the unit each path unit triggers was
+// last started. Like collectTimerResultMetrics it runs once all units were
+// collected, so that the units collected in the same scrape are not asked
+// again.
+func (c *Collector) collectPathTriggerMetrics(conn *dbus.Conn, ch chan<- prometheus.Metric, s *scrape) {
+	for path, triggered := range s.pathUnits {
+		lastTrigger, ok := s.lastStarts[triggered]
+		if !ok {
+			var err error
+			lastTrigger, err = newUnitProperties(conn, triggered).getUint64("Unit", "InactiveExitTimestamp")
+			if err != nil {
+				c.logger.With("unit", path).Warnf(errUnitMetricsMsg, err)
+				continue
+			}
+		}
+
+		if lastTrigger > 0 {
+			ch <- prometheus.MustNewConstMetric(
+				c.pathLastTriggerDesc, prometheus.GaugeValue,
+				float64(lastTrigger)/1e6, path)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.pathTriggersDesc, prometheus.CounterValue,
+			float64(c.paths.observe(path, lastTrigger)), path)
+	}
+}
+
+func (c *Collector) collectAutomountMetrics(ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties, activeStates map[string]string) error {
+	where, err := props.getString("Automount", "Where")
+	if err != nil {
+		return err
+	}
+	directoryMode, err := props.getUint32("Automount", "DirectoryMode")
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.automountInfoDesc, prometheus.GaugeValue, 1.0,
+		unit.Name, where, fmt.Sprintf("%04o", directoryMode))
+
+	timeoutIdle, err := props.getUint64("Automount", "TimeoutIdleUSec")
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.automountTimeoutIdleDesc, prometheus.GaugeValue,
+		float64(timeoutIdle)/1e6, unit.Name)
+
+	result, err := props.getString("Automount", "Result")
+	if err != nil {
+		return err
+	}
+	collectStateSet(ch, c.automountResultDesc, automountResults, result, unit.Name)
+
+	// The mount unit is the one the automount unit triggers, the same one
+	// systemd derives from Where
+	triggers, err := props.get("Unit", "Triggers")
+	if err != nil {
+		return err
+	}
+	// Mount units systemd does not have loaded are not mounted
+	mounts, _ := triggers.([]string)
+	for _, mount := range mounts {
+		mounted := 0.0
+		if activeStates[mount] == "active" {
+			mounted = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.automountMountedDesc, prometheus.GaugeValue,
+			mounted, unit.Name, mount)
 	}
-	return dbus.New()
+
+	return nil
+}
+
+// targetDependencies maps the dependency properties of targets to the
+// dependency label they are exported with
+var targetDependencies = map[string]string{
+	"Requires": "requires",
+	"Wants":    "wants",
+	"BindsTo":  "binds_to",
+}
+
+// collectTargetMetrics counts the units a target depends on by their active
+// state, as a target stays active no matter what happens to the units it
+// only wants. Units systemd does not have loaded, or whose state is not known
+// yet, count as inactive.
+func (c *Collector) collectTargetMetrics(ch chan<- prometheus.Metric, unit dbus.UnitStatus, props *unitProperties, activeStates map[string]string) error {
+	for propName, dependency := range targetDependencies {
+		val, err := props.get("Unit", propName)
+		if err != nil {
+			return err
+		}
+		deps, _ := val.([]string)
+
+		counts := make(map[string]int, len(unitStatesName))
+		for _, dep := range deps {
+			state := activeStates[dep]
+			if state == "" {
+				state = "inactive"
+			}
+			counts[state]++
+		}
+		states := unitStatesName
+		for state := range counts {
+			states = stateSet(states, state)
+		}
+		for _, state := range states {
+			ch <- prometheus.MustNewConstMetric(
+				c.targetDependenciesDesc, prometheus.GaugeValue,
+				float64(counts[state]), unit.Name, dependency, state)
+		}
+	}
+
+	return nil
+}
+
+func (c *Collector) collectStateTransitions(ch chan<- prometheus.Metric) {
+	for t, count := range c.watcher.stateTransitions() {
+		if !c.unitWhitelistPattern.MatchString(t.name) || c.unitBlacklistPattern.MatchString(t.name) {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.unitStateTransitionsDesc, prometheus.CounterValue,
+			float64(count), t.name, t.unitType, t.from, t.to)
+	}
+}
+
+// collectManagerMetrics exports the state of the manager, props are its
+// properties or nil if they could not be read
+func (c *Collector) collectManagerMetrics(ch chan<- prometheus.Metric, props map[string]interface{}) {
+	ch <- prometheus.MustNewConstMetric(
+		c.reloadsDesc, prometheus.CounterValue, float64(c.reloads.count()))
+
+	if props == nil {
+		return
+	}
+
+	if state, ok := props["SystemState"].(string); ok {
+		collectStateSet(ch, c.systemStateDesc, systemStates, state)
+	}
+
+	counts := []struct {
+		propName  string
+		desc      *prometheus.Desc
+		valueType prometheus.ValueType
+	}{
+		{"NFailedUnits", c.failedUnitsDesc, prometheus.GaugeValue},
+		{"NJobs", c.jobsDesc, prometheus.GaugeValue},
+		{"NInstalledJobs", c.installedJobsDesc, prometheus.CounterValue},
+		{"NFailedJobs", c.failedJobsDesc, prometheus.CounterValue},
+	}
+	for _, count := range counts {
+		val, ok := props[count.propName].(uint32)
+		if !ok {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			count.desc, count.valueType, float64(val))
+	}
+
+	virtualization, _ := props["Virtualization"].(string)
+	architecture, _ := props["Architecture"].(string)
+	features, _ := props["Features"].(string)
+	ch <- prometheus.MustNewConstMetric(
+		c.managerInfoDesc, prometheus.GaugeValue, 1.0,
+		virtualization, architecture, features)
+
+	// Older systemd versions lack some of the settings, skip those
+	for propName, resource := range defaultAccountingProperties {
+		enabled, ok := props[propName].(bool)
+		if !ok {
+			continue
+		}
+		enabledVal := 0.0
+		if enabled {
+			enabledVal = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.defaultAccountingDesc, prometheus.GaugeValue, enabledVal, resource)
+	}
+
+	c.collectBootMetrics(ch, props)
+}
+
+// collectBootMetrics computes the durations of the boot phases the same way
+// systemd-analyze time does. The firmware and loader timestamps count
+// backwards from the start of the kernel, all others count forward from it.
+func (c *Collector) collectBootMetrics(ch chan<- prometheus.Metric, props map[string]interface{}) {
+	timestamp := func(propName string) uint64 {
+		val, _ := props[propName].(uint64)
+		return val
+	}
+
+	if kernel := timestamp("KernelTimestamp"); kernel > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.kernelTimestampDesc, prometheus.GaugeValue,
+			float64(kernel)/1000000.0)
+	}
+
+	for phase, usec := range bootPhases(timestamp) {
+		ch <- prometheus.MustNewConstMetric(
+			c.bootDurationDesc, prometheus.GaugeValue,
+			float64(usec)/1000000.0, phase)
+	}
+}
+
+// bootPhases returns the duration of each boot phase in microseconds, or nil
+// while the system is still booting. timestamp looks up a timestamp property
+// of the manager.
+func bootPhases(timestamp func(propName string) uint64) map[string]uint64 {
+	finish := timestamp("FinishTimestampMonotonic")
+	if finish == 0 {
+		// Still booting
+		return nil
+	}
+	firmware := timestamp("FirmwareTimestampMonotonic")
+	loader := timestamp("LoaderTimestampMonotonic")
+	initrd := timestamp("InitRDTimestampMonotonic")
+	userspace := timestamp("UserspaceTimestampMonotonic")
+
+	phases := make(map[string]uint64)
+	if timestamp("KernelTimestamp") > 0 {
+		if firmware > 0 {
+			phases["firmware"] = firmware - loader
+		}
+		if loader > 0 {
+			phases["loader"] = loader
+		}
+		if initrd > 0 {
+			phases["kernel"] = initrd
+			phases["initrd"] = userspace - initrd
+		} else {
+			phases["kernel"] = userspace
+		}
+	} else {
+		// Containers have no kernel of their own to boot and their
+		// monotonic clock counts from the host booting, so like
+		// systemd-analyze time is counted from userspace starting
+		firmware = 0
+		finish -= userspace
+		userspace = 0
+	}
+	phases["userspace"] = finish - userspace
+	phases["total"] = firmware + finish
+
+	// systemd 235 and above also time the steps of starting up the manager
+	steps := map[string]string{
+		"security_setup": "Security",
+		"generators":     "Generators",
+		"units_load":     "UnitsLoad",
+	}
+	for phase, prefix := range steps {
+		start := timestamp(prefix + "StartTimestampMonotonic")
+		end := timestamp(prefix + "FinishTimestampMonotonic")
+		if start > 0 && end >= start {
+			phases[phase] = end - start
+		}
+	}
+
+	return phases
+}
+
+func (c *Collector) collectConnectionMetrics(ch chan<- prometheus.Metric) {
+	connected, reconnects, connectErrors := c.conn.stats()
+
+	connectedVal := 0.0
+	if connected {
+		connectedVal = 1.0
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.dbusConnectedDesc, prometheus.GaugeValue, connectedVal)
+	ch <- prometheus.MustNewConstMetric(
+		c.dbusReconnectsDesc, prometheus.CounterValue, float64(reconnects))
+	ch <- prometheus.MustNewConstMetric(
+		c.dbusConnectErrorsDesc, prometheus.CounterValue, float64(connectErrors))
 }
 
 func filterUnits(units []dbus.UnitStatus, whitelistPattern, blacklistPattern *regexp.Regexp) []dbus.UnitStatus {
@@ -0,0 +1,89 @@
+package systemd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBootPhases(t *testing.T) {
+	tests := []struct {
+		name       string
+		timestamps map[string]uint64
+		want       map[string]uint64
+	}{
+		{
+			name: "still booting",
+			timestamps: map[string]uint64{
+				"KernelTimestamp":             1560000000000000,
+				"UserspaceTimestampMonotonic": 2000000,
+			},
+			want: nil,
+		},
+		{
+			name: "UEFI with initrd",
+			timestamps: map[string]uint64{
+				"KernelTimestamp":                    1560000000000000,
+				"FirmwareTimestampMonotonic":         9000000,
+				"LoaderTimestampMonotonic":           3000000,
+				"InitRDTimestampMonotonic":           1500000,
+				"UserspaceTimestampMonotonic":        4000000,
+				"FinishTimestampMonotonic":           10000000,
+				"SecurityStartTimestampMonotonic":    4100000,
+				"SecurityFinishTimestampMonotonic":   4150000,
+				"GeneratorsStartTimestampMonotonic":  4200000,
+				"GeneratorsFinishTimestampMonotonic": 4300000,
+				"UnitsLoadStartTimestampMonotonic":   4300000,
+				"UnitsLoadFinishTimestampMonotonic":  4500000,
+			},
+			want: map[string]uint64{
+				"firmware":       6000000,
+				"loader":         3000000,
+				"kernel":         1500000,
+				"initrd":         2500000,
+				"userspace":      6000000,
+				"total":          19000000,
+				"security_setup": 50000,
+				"generators":     100000,
+				"units_load":     200000,
+			},
+		},
+		{
+			name: "BIOS without initrd",
+			timestamps: map[string]uint64{
+				"KernelTimestamp":             1560000000000000,
+				"UserspaceTimestampMonotonic": 1200000,
+				"FinishTimestampMonotonic":    5000000,
+			},
+			want: map[string]uint64{
+				"kernel":    1200000,
+				"userspace": 3800000,
+				"total":     5000000,
+			},
+		},
+		{
+			name: "container",
+			timestamps: map[string]uint64{
+				"UserspaceTimestampMonotonic":       86400000000,
+				"FinishTimestampMonotonic":          86402500000,
+				"UnitsLoadStartTimestampMonotonic":  86400100000,
+				"UnitsLoadFinishTimestampMonotonic": 86400300000,
+			},
+			want: map[string]uint64{
+				"userspace":  2500000,
+				"total":      2500000,
+				"units_load": 200000,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bootPhases(func(propName string) uint64 {
+				return tt.timestamps[propName]
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bootPhases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
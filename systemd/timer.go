@@ -0,0 +1,143 @@
+package systemd
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-systemd/dbus"
+	"golang.org/x/sys/unix"
+)
+
+// timerSchedules remembers when each timer was due next, so that once it
+// fires we can tell how late it was compared to its schedule.
+type timerSchedules struct {
+	mu      sync.Mutex
+	entries map[string]timerSchedule
+}
+
+type timerSchedule struct {
+	lastTrigger uint64
+	next        uint64
+	delay       float64
+	hasDelay    bool
+}
+
+func newTimerSchedules() *timerSchedules {
+	return &timerSchedules{entries: make(map[string]timerSchedule)}
+}
+
+// observe records the last and next trigger time of a timer, both in
+// microseconds since epoch, and returns the delay in seconds of the most
+// recent trigger we saw being scheduled. Timers which fired before their
+// schedule, e.g. because it changed, count as on time.
+func (s *timerSchedules) observe(name string, lastTrigger uint64, next uint64) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, known := s.entries[name]
+	if known && lastTrigger != entry.lastTrigger && entry.next > 0 {
+		entry.delay = 0
+		if lastTrigger > entry.next {
+			entry.delay = float64(lastTrigger-entry.next) / 1e6
+		}
+		entry.hasDelay = true
+	}
+	entry.lastTrigger = lastTrigger
+	entry.next = next
+	s.entries[name] = entry
+
+	return entry.delay, entry.hasDelay
+}
+
+// retain forgets about all timers but the given units
+func (s *timerSchedules) retain(units []dbus.UnitStatus) {
+	names := make(map[string]bool, len(units))
+	for _, unit := range units {
+		names[unit.Name] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name := range s.entries {
+		if !names[name] {
+			delete(s.entries, name)
+		}
+	}
+}
+
+// timerNextElapse returns when a timer elapses next in microseconds since
+// epoch, or 0 if it is not scheduled. Like systemctl list-timers this is the
+// earlier of the calendar and the monotonic schedule, the latter converted
+// to wall clock time.
+func timerNextElapse(realtime uint64, monotonic uint64) uint64 {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return earliestElapse(realtime, 0, 0, 0)
+	}
+	return earliestElapse(realtime, monotonic,
+		uint64(time.Now().UnixNano()/1000), uint64(ts.Nano()/1000))
+}
+
+// earliestElapse is timerNextElapse with the current wall clock and
+// monotonic time, both in microseconds, given
+func earliestElapse(realtime, monotonic, nowRealtime, nowMonotonic uint64) uint64 {
+	if realtime == math.MaxUint64 {
+		realtime = 0
+	}
+	if monotonic == 0 || monotonic == math.MaxUint64 || nowMonotonic == 0 {
+		return realtime
+	}
+
+	var converted uint64
+	if monotonic > nowMonotonic {
+		converted = nowRealtime + (monotonic - nowMonotonic)
+	} else {
+		converted = nowRealtime - (nowMonotonic - monotonic)
+	}
+
+	if realtime == 0 || converted < realtime {
+		return converted
+	}
+	return realtime
+}
+
+// formatTimersCalendar turns the TimersCalendar property, a list of
+// (base, calendar spec, next elapse) tuples, into a list of calendar specs
+func formatTimersCalendar(val interface{}) string {
+	entries, _ := val.([][]interface{})
+	specs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if len(entry) < 2 {
+			continue
+		}
+		if spec, ok := entry[1].(string); ok {
+			specs = append(specs, spec)
+		}
+	}
+	return strings.Join(specs, ";")
+}
+
+// formatTimersMonotonic turns the TimersMonotonic property, a list of (base,
+// usec, next elapse) tuples, into a list like "OnBootUSec=15m0s"
+func formatTimersMonotonic(val interface{}) string {
+	entries, _ := val.([][]interface{})
+	specs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if len(entry) < 2 {
+			continue
+		}
+		base, ok := entry[0].(string)
+		if !ok {
+			continue
+		}
+		usec, ok := entry[1].(uint64)
+		if !ok {
+			continue
+		}
+		specs = append(specs, base+"="+(time.Duration(usec)*time.Microsecond).String())
+	}
+	return strings.Join(specs, ";")
+}
@@ -0,0 +1,114 @@
+package systemd
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEarliestElapse(t *testing.T) {
+	const (
+		nowRealtime  = 1560000000000000
+		nowMonotonic = 3600000000
+	)
+	tests := []struct {
+		name      string
+		realtime  uint64
+		monotonic uint64
+		want      uint64
+	}{
+		{
+			name: "not scheduled",
+		},
+		{
+			name:      "infinity",
+			realtime:  math.MaxUint64,
+			monotonic: math.MaxUint64,
+		},
+		{
+			name:     "calendar only",
+			realtime: 1560003600000000,
+			want:     1560003600000000,
+		},
+		{
+			name:      "monotonic only",
+			monotonic: nowMonotonic + 900000000,
+			want:      nowRealtime + 900000000,
+		},
+		{
+			name:      "monotonic in the past",
+			monotonic: nowMonotonic - 1000000,
+			want:      nowRealtime - 1000000,
+		},
+		{
+			name:      "monotonic earlier than calendar",
+			realtime:  1560003600000000,
+			monotonic: nowMonotonic + 60000000,
+			want:      nowRealtime + 60000000,
+		},
+		{
+			name:      "calendar earlier than monotonic",
+			realtime:  1560000060000000,
+			monotonic: nowMonotonic + 3600000000,
+			want:      1560000060000000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := earliestElapse(tt.realtime, tt.monotonic, nowRealtime, nowMonotonic)
+			if got != tt.want {
+				t.Errorf("earliestElapse() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatTimersCalendar(t *testing.T) {
+	tests := []struct {
+		name string
+		val  interface{}
+		want string
+	}{
+		{
+			name: "one spec",
+			val: [][]interface{}{
+				{"OnCalendar", "*-*-* 00:00:00", uint64(1560038400000000)},
+			},
+			want: "*-*-* 00:00:00",
+		},
+		{
+			name: "several specs",
+			val: [][]interface{}{
+				{"OnCalendar", "Mon *-*-* 06:00:00", uint64(0)},
+				{"OnCalendar", "*-*-01 00:00:00", uint64(0)},
+			},
+			want: "Mon *-*-* 06:00:00;*-*-01 00:00:00",
+		},
+		{
+			name: "none",
+			val:  [][]interface{}{},
+			want: "",
+		},
+		{
+			name: "malformed entries",
+			val: [][]interface{}{
+				{"OnCalendar"},
+				{"OnCalendar", uint64(1)},
+			},
+			want: "",
+		},
+		{
+			name: "wrong type",
+			val:  "daily",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatTimersCalendar(tt.val); got != tt.want {
+				t.Errorf("formatTimersCalendar() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package systemd
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-systemd/dbus"
+	godbus "github.com/godbus/dbus"
+	"github.com/pkg/errors"
+)
+
+// systemdVersion tracks the version of the systemd we are connected to, so
+// that properties older versions lack are neither asked for nor turned into
+// errors. The version is detected whenever a connection is established, as
+// systemd may have been upgraded and re-executed by the time we have to
+// reconnect. It does not care about any signals, it only subscribes to the
+// connection to be told about new ones.
+type systemdVersion struct {
+	mu      sync.Mutex
+	version string
+	major   int
+}
+
+func (v *systemdVersion) matchRules() []string {
+	return nil
+}
+
+func (v *systemdVersion) connected(conn *dbus.Conn) error {
+	return v.detect(conn)
+}
+
+func (v *systemdVersion) signal(signal *godbus.Signal) {}
+
+// detect reads the version of systemd from the manager
+func (v *systemdVersion) detect(conn *dbus.Conn) error {
+	// GetManagerProperty formats the value, strings come back quoted
+	quoted, err := conn.GetManagerProperty("Version")
+	if err != nil {
+		return errors.Wrap(err, "couldn't get systemd version")
+	}
+	version, err := strconv.Unquote(quoted)
+	if err != nil {
+		return errors.Errorf("couldn't unquote systemd version %s", quoted)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.version = version
+	v.major = parseSystemdVersion(version)
+	return nil
+}
+
+// get returns the version string and the major version number, which are
+// empty and 0 if the version is unknown
+func (v *systemdVersion) get() (string, int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.version, v.major
+}
+
+// known reports whether the version of systemd could be detected
+func (v *systemdVersion) known() bool {
+	_, major := v.get()
+	return major > 0
+}
+
+// atLeast reports whether systemd is known to be of the given major version
+// or newer
+func (v *systemdVersion) atLeast(major int) bool {
+	_, current := v.get()
+	return current >= major
+}
+
+// parseSystemdVersion returns the major version number of a version string
+// as reported by systemd, e.g. "239", "245.4-4ubuntu3" or "v256~rc3", or 0
+// if it cannot be parsed.
+func parseSystemdVersion(version string) int {
+	version = strings.TrimPrefix(version, "v")
+	end := strings.IndexFunc(version, func(r rune) bool { return r < '0' || r > '9' })
+	if end >= 0 {
+		version = version[:end]
+	}
+	major, err := strconv.Atoi(version)
+	if err != nil {
+		return 0
+	}
+	return major
+}
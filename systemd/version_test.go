@@ -0,0 +1,27 @@
+package systemd
+
+import "testing"
+
+func TestParseSystemdVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    int
+	}{
+		{version: "239", want: 239},
+		{version: "245.4-4ubuntu3", want: 245},
+		{version: "252.22-1~deb12u1", want: 252},
+		{version: "219-78.el7_9.7", want: 219},
+		{version: "v256~rc3", want: 256},
+		{version: "256.4+suse.15.g8a1a5b6f5a", want: 256},
+		{version: "", want: 0},
+		{version: "unknown", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			if got := parseSystemdVersion(tt.version); got != tt.want {
+				t.Errorf("parseSystemdVersion(%q) = %d, want %d", tt.version, got, tt.want)
+			}
+		})
+	}
+}
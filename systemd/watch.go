@@ -0,0 +1,299 @@
+package systemd
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-systemd/dbus"
+	godbus "github.com/godbus/dbus"
+)
+
+const (
+	signalUnitNew           = "org.freedesktop.systemd1.Manager.UnitNew"
+	signalUnitRemoved       = "org.freedesktop.systemd1.Manager.UnitRemoved"
+	signalReloading         = "org.freedesktop.systemd1.Manager.Reloading"
+	signalPropertiesChanged = "org.freedesktop.DBus.Properties.PropertiesChanged"
+	unitInterfaceName       = "org.freedesktop.systemd1.Unit"
+	unitPathPrefix          = "/org/freedesktop/systemd1/unit/"
+
+	// errAlreadySubscribed is returned by systemd if a client subscribes
+	// more than once
+	errAlreadySubscribed = "org.freedesktop.systemd1.AlreadySubscribed"
+
+	// minRelistInterval limits how often units systemd announces get the
+	// table listed again
+	minRelistInterval = time.Minute
+)
+
+// unitWatcher keeps a table of all units systemd has loaded, fed by the
+// UnitNew, UnitRemoved and PropertiesChanged signals, so that scrapes do not
+// have to list all units every time. The table is (re-)built with a full
+// ListUnits whenever it might have missed something: right after connecting
+// and once a daemon-reload finished, which removes and re-adds all units.
+// UnitNew only tells the name of a unit, so units systemd announces are listed
+// as well, but at most once every minRelistInterval, as hosts which keep
+// creating transient units would otherwise be listed on nearly every scrape.
+// Asking systemd about a single unit instead would load it if it is gone
+// again, and cause yet another UnitNew.
+// Along the way it counts the ActiveState transitions of every unit, which
+// catches units that fail and get restarted in between two scrapes.
+type unitWatcher struct {
+	mu          sync.Mutex
+	units       map[godbus.ObjectPath]dbus.UnitStatus
+	synced      bool
+	transitions map[stateTransition]uint64
+	// unlisted is set when units were added which were not listed yet,
+	// listed is when the table was last listed
+	unlisted bool
+	listed   time.Time
+}
+
+// stateTransition identifies a change of a unit's ActiveState
+type stateTransition struct {
+	name     string
+	unitType string
+	from     string
+	to       string
+}
+
+func newUnitWatcher() *unitWatcher {
+	return &unitWatcher{
+		units:       make(map[godbus.ObjectPath]dbus.UnitStatus),
+		transitions: make(map[stateTransition]uint64),
+	}
+}
+
+// matchRules covers UnitRemoved and Reloading, Subscribe already asks for
+// UnitNew and PropertiesChanged
+func (w *unitWatcher) matchRules() []string {
+	return []string{
+		"type='signal',interface='org.freedesktop.systemd1.Manager',member='UnitRemoved'",
+		"type='signal',interface='org.freedesktop.systemd1.Manager',member='Reloading'",
+	}
+}
+
+func (w *unitWatcher) connected(conn *dbus.Conn) error {
+	w.mu.Lock()
+	w.synced = false
+	w.mu.Unlock()
+
+	return subscribeManager(conn)
+}
+
+// subscribeManager asks systemd to emit its signals to us. Subscribing again
+// on the same connection is not an error.
+func subscribeManager(conn *dbus.Conn) error {
+	err := conn.Subscribe()
+	if dbusErr, ok := err.(godbus.Error); ok && dbusErr.Name == errAlreadySubscribed {
+		return nil
+	}
+	return err
+}
+
+func (w *unitWatcher) signal(signal *godbus.Signal) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch signal.Name {
+	case signalUnitNew:
+		if len(signal.Body) < 2 {
+			return
+		}
+		path, ok := signal.Body[1].(godbus.ObjectPath)
+		if !ok {
+			return
+		}
+		if _, known := w.units[path]; !known {
+			// Keep track of the unit's state changes until the next
+			// listing fills in the rest
+			name, _ := signal.Body[0].(string)
+			w.units[path] = dbus.UnitStatus{Name: name, Path: path}
+			w.unlisted = true
+		}
+	case signalUnitRemoved:
+		if len(signal.Body) < 2 {
+			return
+		}
+		path, ok := signal.Body[1].(godbus.ObjectPath)
+		if !ok {
+			return
+		}
+		delete(w.units, path)
+	case signalReloading:
+		if len(signal.Body) < 1 {
+			return
+		}
+		// Reloading is sent with true when a reload starts and with
+		// false once it is done, and all units are new by then
+		if active, ok := signal.Body[0].(bool); ok && !active {
+			w.synced = false
+		}
+	case signalPropertiesChanged:
+		if len(signal.Body) < 2 {
+			return
+		}
+		if iface, ok := signal.Body[0].(string); !ok || iface != unitInterfaceName {
+			return
+		}
+		changed, ok := signal.Body[1].(map[string]godbus.Variant)
+		if !ok {
+			return
+		}
+		unit, known := w.units[signal.Path]
+		if !known {
+			// We missed the UnitNew, e.g. because it was sent before
+			// we subscribed
+			name := unitNameFromPath(signal.Path)
+			if name == "" {
+				return
+			}
+			unit = dbus.UnitStatus{Name: name, Path: signal.Path}
+			w.unlisted = true
+		}
+		if v, ok := changed["ActiveState"]; ok {
+			if state, ok := v.Value().(string); ok {
+				w.countTransition(unit, state)
+			}
+		}
+		updateUnitStatus(&unit, changed)
+		w.units[signal.Path] = unit
+	}
+}
+
+// list returns all units in the table, listing them from systemd first
+// if the table might be out of date.
+func (w *unitWatcher) list(conn *dbus.Conn) ([]dbus.UnitStatus, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.synced || (w.unlisted && time.Since(w.listed) >= minRelistInterval) {
+		units, err := conn.ListUnits()
+		if err != nil {
+			return nil, err
+		}
+		// Count the changes we missed signals for, as far as they can be
+		// told from comparing the old table to the new listing
+		for _, unit := range units {
+			if old, known := w.units[unit.Path]; known {
+				w.countTransition(old, unit.ActiveState)
+			}
+		}
+		w.units = make(map[godbus.ObjectPath]dbus.UnitStatus, len(units))
+		names := make(map[string]bool, len(units))
+		for _, unit := range units {
+			w.units[unit.Path] = unit
+			names[unit.Name] = true
+		}
+		w.pruneTransitions(names)
+		w.synced = true
+		w.unlisted = false
+		w.listed = time.Now()
+	}
+
+	units := make([]dbus.UnitStatus, 0, len(w.units))
+	for _, unit := range w.units {
+		units = append(units, unit)
+	}
+	return units, nil
+}
+
+// stateTransitions returns a copy of the ActiveState transition counters
+func (w *unitWatcher) stateTransitions() map[stateTransition]uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	transitions := make(map[stateTransition]uint64, len(w.transitions))
+	for t, count := range w.transitions {
+		transitions[t] = count
+	}
+	return transitions
+}
+
+// countTransition records unit changing its ActiveState to state. Units we
+// only know the name of yet have no state to transition from.
+func (w *unitWatcher) countTransition(unit dbus.UnitStatus, state string) {
+	if unit.ActiveState == "" || unit.ActiveState == state {
+		return
+	}
+	w.transitions[stateTransition{
+		name:     unit.Name,
+		unitType: parseUnitType(unit),
+		from:     unit.ActiveState,
+		to:       state,
+	}]++
+}
+
+// pruneTransitions drops the counters of units systemd no longer has loaded,
+// otherwise every transient unit ever seen would be kept around forever. This
+// is done on listing rather than on UnitRemoved, as daemon-reload removes and
+// re-adds all units.
+func (w *unitWatcher) pruneTransitions(loaded map[string]bool) {
+	for t := range w.transitions {
+		if !loaded[t.name] {
+			delete(w.transitions, t)
+		}
+	}
+}
+
+// unitNameFromPath reverses the escaping systemd applies to unit names in
+// their object paths, e.g. /org/freedesktop/systemd1/unit/foo_2eservice is
+// foo.service. It returns "" for paths which do not belong to a unit.
+func unitNameFromPath(path godbus.ObjectPath) string {
+	label := strings.TrimPrefix(string(path), unitPathPrefix)
+	if label == string(path) || label == "" || label == "_" {
+		return ""
+	}
+
+	var name strings.Builder
+	for i := 0; i < len(label); i++ {
+		if label[i] == '_' && i+3 <= len(label) {
+			if c, err := strconv.ParseUint(label[i+1:i+3], 16, 8); err == nil {
+				name.WriteByte(byte(c))
+				i += 2
+				continue
+			}
+		}
+		name.WriteByte(label[i])
+	}
+	return name.String()
+}
+
+// updateUnitStatus applies the properties systemd reported as changed to unit
+func updateUnitStatus(unit *dbus.UnitStatus, changed map[string]godbus.Variant) {
+	for name, value := range changed {
+		switch name {
+		case "LoadState":
+			if s, ok := value.Value().(string); ok {
+				unit.LoadState = s
+			}
+		case "ActiveState":
+			if s, ok := value.Value().(string); ok {
+				unit.ActiveState = s
+			}
+		case "SubState":
+			if s, ok := value.Value().(string); ok {
+				unit.SubState = s
+			}
+		case "Description":
+			if s, ok := value.Value().(string); ok {
+				unit.Description = s
+			}
+		case "Following":
+			if s, ok := value.Value().(string); ok {
+				unit.Followed = s
+			}
+		case "Job":
+			// (uo) tuple of job id and job object path
+			if job, ok := value.Value().([]interface{}); ok && len(job) == 2 {
+				if id, ok := job[0].(uint32); ok {
+					unit.JobId = id
+				}
+				if path, ok := job[1].(godbus.ObjectPath); ok {
+					unit.JobPath = path
+				}
+			}
+		}
+	}
+}
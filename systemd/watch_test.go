@@ -0,0 +1,80 @@
+package systemd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/go-systemd/dbus"
+	godbus "github.com/godbus/dbus"
+)
+
+func TestUnitNameFromPath(t *testing.T) {
+	tests := []struct {
+		path godbus.ObjectPath
+		want string
+	}{
+		{path: "/org/freedesktop/systemd1/unit/sshd_2eservice", want: "sshd.service"},
+		{path: "/org/freedesktop/systemd1/unit/user_401000_2eservice", want: "user@1000.service"},
+		{path: "/org/freedesktop/systemd1/unit/session_2d3_2escope", want: "session-3.scope"},
+		{path: "/org/freedesktop/systemd1/unit/dev_2ddisk_2dby_5cx2duuid_2emount", want: `dev-disk-by\x2duuid.mount`},
+		{path: "/org/freedesktop/systemd1/unit/_5f_2eservice", want: "_.service"},
+		{path: "/org/freedesktop/systemd1/job/42", want: ""},
+		{path: "/org/freedesktop/systemd1", want: ""},
+		{path: "/org/freedesktop/systemd1/unit/_", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.path), func(t *testing.T) {
+			if got := unitNameFromPath(tt.path); got != tt.want {
+				t.Errorf("unitNameFromPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnitWatcherSignal(t *testing.T) {
+	const fooPath = godbus.ObjectPath("/org/freedesktop/systemd1/unit/foo_2eservice")
+	const barPath = godbus.ObjectPath("/org/freedesktop/systemd1/unit/bar_2eservice")
+
+	w := newUnitWatcher()
+	w.units[fooPath] = dbus.UnitStatus{Name: "foo.service", Path: fooPath, LoadState: "loaded", ActiveState: "active"}
+	w.synced = true
+
+	w.signal(&godbus.Signal{
+		Path: fooPath,
+		Name: signalPropertiesChanged,
+		Body: []interface{}{unitInterfaceName, map[string]godbus.Variant{
+			"ActiveState": godbus.MakeVariant("failed"),
+			"SubState":    godbus.MakeVariant("failed"),
+		}},
+	})
+	if got := w.units[fooPath]; got.ActiveState != "failed" || got.SubState != "failed" {
+		t.Errorf("unit after PropertiesChanged = %+v, want it failed", got)
+	}
+	want := map[stateTransition]uint64{{name: "foo.service", unitType: "service", from: "active", to: "failed"}: 1}
+	if got := w.stateTransitions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("stateTransitions() = %v, want %v", got, want)
+	}
+
+	w.signal(&godbus.Signal{Name: signalUnitNew, Body: []interface{}{"bar.service", barPath}})
+	if got := w.units[barPath]; got.Name != "bar.service" {
+		t.Errorf("unit after UnitNew = %+v, want bar.service", got)
+	}
+	if !w.synced || !w.unlisted {
+		t.Errorf("after UnitNew synced = %v, unlisted = %v, want true, true", w.synced, w.unlisted)
+	}
+
+	w.signal(&godbus.Signal{Name: signalUnitRemoved, Body: []interface{}{"bar.service", barPath}})
+	if _, ok := w.units[barPath]; ok {
+		t.Error("unit still in the table after UnitRemoved")
+	}
+
+	w.signal(&godbus.Signal{Name: signalReloading, Body: []interface{}{true}})
+	if !w.synced {
+		t.Error("table out of sync once a reload started")
+	}
+	w.signal(&godbus.Signal{Name: signalReloading, Body: []interface{}{false}})
+	if w.synced {
+		t.Error("table still in sync once a reload finished")
+	}
+}